@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// 子命令退出码
+const (
+	exitOK        = 0 // 成功
+	exitUnhealthy = 1 // 配置无效，或有进程处于 error、failed 状态
+	exitFailure   = 2 // 参数错误或无法连接 keeper
+)
+
+// configPathArg 返回使用的配置文件路径，兼容位置参数形式。
+// 同时使用 --config 和位置参数，或有多个位置参数时返回错误，避免静默忽略其中之一
+func configPathArg(flags *flag.FlagSet, configPath string) (string, error) {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "config" {
+			set = true
+		}
+	})
+	switch {
+	case flags.NArg() == 0:
+		return configPath, nil
+	case flags.NArg() > 1:
+		return "", fmt.Errorf("只能指定一个配置文件，多余的参数: %s", strings.Join(flags.Args()[1:], " "))
+	case set:
+		return "", fmt.Errorf("--config 与位置参数 %s 不能同时指定配置文件", flags.Arg(0))
+	}
+	return flags.Arg(0), nil
+}
+
+// parseFailure 返回子命令参数解析失败时的退出码，-h 显示帮助不算失败
+func parseFailure(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	return exitFailure
+}
+
+// readConfigOnce 读取并校验配置，不创建默认配置、不启动任何进程，供子命令使用
+func readConfigOnce(configPath, format, profile string, overrides ServerOverrides) (*ProcessManager, *Config, error) {
+	pm, config, err := readMergedConfig(configPath, format, profile, overrides)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := pm.validateConfig(config); err != nil {
+		return nil, nil, fmt.Errorf("配置验证失败: %v", err)
+	}
+	return pm, config, nil
+}
+
+// readMergedConfig 读取配置并合并 include、profile 和命令行覆盖的服务器参数，不做校验
+func readMergedConfig(configPath, format, profile string, overrides ServerOverrides) (*ProcessManager, *Config, error) {
+	pm := NewProcessManager(configPath, overrides)
+	pm.configFormat = format
+	pm.profile = profile
+	if pm.profile == "" {
+		pm.profile = os.Getenv(profileEnvVar)
+	}
+
+	var config Config
+	if isStdinConfig(configPath) || isURLConfig(configPath) {
+		if _, err := readConfigSource(configPath, format, &config); err != nil {
+			return nil, nil, err
+		}
+	} else if err := readConfigFile(configPath, &config); err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := pm.mergeConfig(&config); err != nil {
+		return nil, nil, err
+	}
+	pm.applyOverrides(&config)
+	return pm, &config, nil
+}
+
+// runValidate 检查配置文件，输出启动前检查发现的问题。配置无效时返回 exitUnhealthy
+func runValidate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	configPath := flags.String("config", "keeper.yaml", "配置文件路径（支持 .json, .yaml, .yml），- 表示从标准输入读取，也可以是 http(s):// 地址")
+	configFormat := flags.String("config-format", "", "标准输入或 URL 配置的格式：json, yaml，默认根据 Content-Type、扩展名或内容推断")
+	profile := flags.String("profile", "", "使用的配置 profile，默认读取环境变量 "+profileEnvVar)
+	if err := flags.Parse(args); err != nil {
+		return parseFailure(err)
+	}
+	format, err := parseConfigFormat(*configFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	path, err := configPathArg(flags, *configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	pm, config, err := readConfigOnce(path, format, *profile, ServerOverrides{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "配置无效: %v\n", err)
+		return exitUnhealthy
+	}
+
+	fmt.Printf("配置有效: %s，%d 个进程\n", displayConfigPath(path), len(config.Processes))
+	for _, processConfig := range config.Processes {
+		if result := pm.preflight(processConfig); !result.OK() {
+			fmt.Printf("警告: 进程 %s 将无法启动: %s\n", processConfig.Name, result.Note)
+		}
+	}
+	return exitOK
+}
+
+// runStatus 通过运行中 keeper 的 API 查询所有进程状态，以表格或 JSON 输出。
+// 有进程处于 error、failed 状态时返回 exitUnhealthy，便于在脚本和健康检查中使用
+func runStatus(args []string) int {
+	var overrides ServerOverrides
+	flags := flag.NewFlagSet("status", flag.ContinueOnError)
+	configPath := flags.String("config", "keeper.yaml", "配置文件路径，用于确定 keeper 的监听地址")
+	configFormat := flags.String("config-format", "", "标准输入或 URL 配置的格式：json, yaml")
+	profile := flags.String("profile", "", "使用的配置 profile，默认读取环境变量 "+profileEnvVar+"，需与 keeper 运行时一致")
+	flags.StringVar(&overrides.Host, "host", "", "keeper 的监听地址，覆盖配置文件中的 server.host")
+	flags.StringVar(&overrides.Port, "port", "", "keeper 的端口，覆盖配置文件中的 server.port")
+	addr := flags.String("addr", "", "keeper 的地址（如 127.0.0.1:8080、http://host:8080、unix:/path/keeper.sock），设置后不读取配置文件")
+	jsonOutput := flags.Bool("json", false, "以 JSON 输出进程状态")
+	timeout := flags.Duration("timeout", 5*time.Second, "请求超时时间")
+	if err := flags.Parse(args); err != nil {
+		return parseFailure(err)
+	}
+
+	target := *addr
+	if target == "" {
+		format, err := parseConfigFormat(*configFormat)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		path, err := configPathArg(flags, *configPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		// 只需要监听地址，其他配置项无效时仍可查询
+		_, config, err := readMergedConfig(path, format, *profile, overrides)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "读取配置失败: %v\n", err)
+			return exitFailure
+		}
+		if err := validateListen(&config.Server); err != nil {
+			fmt.Fprintf(os.Stderr, "无法确定 keeper 地址: %v\n", err)
+			return exitFailure
+		}
+		target, err = keeperAddress(config.Server)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "无法确定 keeper 地址: %v\n", err)
+			return exitFailure
+		}
+	}
+
+	body, err := fetchStatus(target, *timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "查询进程状态失败: %v\n", err)
+		return exitFailure
+	}
+	var processes []*ProcessStatus
+	if err := json.Unmarshal(body, &processes); err != nil {
+		fmt.Fprintf(os.Stderr, "解析进程状态失败: %v\n", err)
+		return exitFailure
+	}
+
+	if *jsonOutput {
+		var indented bytes.Buffer
+		json.Indent(&indented, body, "", "  ")
+		fmt.Println(strings.TrimSpace(indented.String()))
+	} else {
+		printStatusTable(os.Stdout, processes, time.Now())
+	}
+
+	for _, status := range processes {
+		if status.Status == "error" || status.Status == "failed" {
+			return exitUnhealthy
+		}
+	}
+	return exitOK
+}
+
+// keeperAddress 根据服务器配置返回连接 keeper 使用的地址，监听所有地址时连接本机回环地址
+func keeperAddress(server ServerConfig) (string, error) {
+	targets, err := listenTargets(server)
+	if err != nil {
+		return "", err
+	}
+	if len(targets) == 0 {
+		return "", fmt.Errorf("没有监听地址")
+	}
+
+	target := targets[0]
+	if target.network == "unix" {
+		return "unix:" + target.address, nil
+	}
+	host, port, err := net.SplitHostPort(target.address)
+	if err != nil {
+		return "", err
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+		if ip != nil && ip.To4() == nil {
+			host = "::1"
+		}
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// fetchStatus 请求 keeper 的 /api/status，address 可以是 host:port、http(s):// 地址或 unix:套接字路径
+func fetchStatus(address string, timeout time.Duration) ([]byte, error) {
+	client := &http.Client{Timeout: timeout}
+	baseURL := strings.TrimSuffix(address, "/")
+	if socket, ok := strings.CutPrefix(address, "unix:"); ok {
+		client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}
+		baseURL = "http://unix"
+	} else if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
+		baseURL = "http://" + baseURL
+	}
+
+	resp, err := client.Get(baseURL + "/api/status")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// printStatusTable 按 API 返回的顺序（与 Web 界面一致）输出状态表格，表头使用英文以便按列对齐
+func printStatusTable(w io.Writer, processes []*ProcessStatus, now time.Time) {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tSTATUS\tPID\tUPTIME\tRESTARTS\tEXIT\tERROR")
+	for _, status := range processes {
+		name := status.Config.Name
+		pid, uptime := "-", "-"
+		if status.PID != 0 {
+			pid = fmt.Sprint(status.PID)
+		}
+		if status.PID != 0 && !status.StartTime.IsZero() {
+			uptime = now.Sub(status.StartTime).Round(time.Second).String()
+		}
+		lastError := status.LastError
+		if lastError == "" {
+			lastError = "-"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%d\t%d\t%s\n", name, status.Status, pid, uptime, status.Restarts, status.LastExitCode, lastError)
+	}
+	table.Flush()
+}
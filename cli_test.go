@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestStatusNeedsOnlyListenSettings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "[]")
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 进程配置无效不影响查询状态
+	path := filepath.Join(t.TempDir(), "keeper.yaml")
+	writeTestConfig(t, path, fmt.Sprintf(`
+server:
+  host: %s
+  port: "%s"
+processes:
+  - name: web
+    command: fake-sleep
+    watch_debounce: -1
+profiles:
+  prod:
+    processes:
+      - name: web
+        args: ["--prod"]
+`, host, port))
+
+	if code := runStatus([]string{"--profile", "prod", path}); code != exitOK {
+		t.Fatalf("退出码为 %d，期望 %d", code, exitOK)
+	}
+	if code := runStatus([]string{"--profile", "missing", path}); code != exitFailure {
+		t.Fatalf("profile 不存在时退出码为 %d，期望 %d", code, exitFailure)
+	}
+}
+
+func TestConfigPathArg(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{args: nil, want: "keeper.yaml"},
+		{args: []string{"a.yaml"}, want: "a.yaml"},
+		{args: []string{"--config", "b.yaml"}, want: "b.yaml"},
+		{args: []string{"--config", "b.yaml", "a.yaml"}, wantErr: true},
+		{args: []string{"a.yaml", "c.yaml"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		configPath := flags.String("config", "keeper.yaml", "")
+		if err := flags.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		got, err := configPathArg(flags, *configPath)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%q: 得到 %q, %v", tt.args, got, err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// pendingStart 因达到 max_concurrent 上限而排队等待启动的进程
+type pendingStart struct {
+	name   string
+	forced bool // 强制启动未启用的进程
+}
+
+// activeCount 返回正在运行（含 starting、paused）的进程数量，调用者需持有锁
+func (pm *ProcessManager) activeCount() int {
+	count := 0
+	for _, status := range pm.processes {
+		if status.Status == "running" || status.Status == "starting" || status.Status == "paused" {
+			count++
+		}
+	}
+	return count
+}
+
+// concurrencyLimitReached 判断运行中的进程数是否已达到 max_concurrent 上限，调用者需持有锁
+func (pm *ProcessManager) concurrencyLimitReached() bool {
+	if pm.config == nil || pm.config.Server.MaxConcurrent <= 0 {
+		return false
+	}
+	return pm.activeCount() >= pm.config.Server.MaxConcurrent
+}
+
+// queueStart 把进程加入启动队列并标记为 pending，有进程退出后按排队顺序启动，调用者需持有锁
+func (pm *ProcessManager) queueStart(name string, status *ProcessStatus, forced bool) {
+	pm.pending = append(pm.pending, pendingStart{name: name, forced: forced})
+	status.Status = "pending"
+	pm.addLog(name, fmt.Sprintf("INFO: 运行中的进程已达到上限 (%d个)，排队等待启动", pm.config.Server.MaxConcurrent))
+	logInfof("进程 %s 排队等待启动，运行中的进程已达到上限 (%d个)", name, pm.config.Server.MaxConcurrent)
+}
+
+// cancelPending 把进程从启动队列中移除，调用者需持有锁
+func (pm *ProcessManager) cancelPending(name string) {
+	pm.pending = slices.DeleteFunc(pm.pending, func(entry pendingStart) bool {
+		return entry.name == name
+	})
+}
+
+// startPending 有空闲名额时按排队顺序启动等待中的进程。
+// 排队期间已被停止、移除或重新添加（状态不再是 pending）的进程直接跳过
+func (pm *ProcessManager) startPending() {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	for len(pm.pending) > 0 && !pm.concurrencyLimitReached() {
+		entry := pm.pending[0]
+		pm.pending = pm.pending[1:]
+
+		status, exists := pm.processes[entry.name]
+		if !exists || status.Status != "pending" {
+			continue
+		}
+		if !status.Config.Enabled && !entry.forced {
+			status.Status = "stopped"
+			pm.addLog(entry.name, "INFO: 进程已被禁用，取消排队")
+			continue
+		}
+
+		pm.addLog(entry.name, "INFO: 有空闲名额，开始启动")
+		if err := pm.launchProcess(entry.name, status, entry.forced); err != nil {
+			logErrorf("启动排队的进程 %s 失败: %v", entry.name, err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// stdinConfigPath 表示从标准输入读取配置
+const stdinConfigPath = "-"
+
+// configFetchTimeout 从 URL 获取配置的超时时间
+const configFetchTimeout = 30 * time.Second
+
+// isStdinConfig 判断配置是否从标准输入读取
+func isStdinConfig(configPath string) bool {
+	return configPath == stdinConfigPath
+}
+
+// isURLConfig 判断配置是否从 HTTP(S) 地址获取
+func isURLConfig(configPath string) bool {
+	return strings.HasPrefix(configPath, "http://") || strings.HasPrefix(configPath, "https://")
+}
+
+// configDir 返回解析相对路径使用的目录，标准输入和 URL 配置使用当前工作目录
+func configDir(configPath string) string {
+	if isStdinConfig(configPath) || isURLConfig(configPath) {
+		return "."
+	}
+	return filepath.Dir(configPath)
+}
+
+// displayConfigPath 返回用于日志和页面展示的配置路径，隐藏 URL 中的密码
+func displayConfigPath(configPath string) string {
+	if !isURLConfig(configPath) {
+		return configPath
+	}
+	u, err := url.Parse(configPath)
+	if err != nil {
+		return configPath
+	}
+	return u.Redacted()
+}
+
+// parseConfigFormat 规范化 --config-format 参数
+func parseConfigFormat(format string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "":
+		return "", nil
+	case "json":
+		return "json", nil
+	case "yaml", "yml":
+		return "yaml", nil
+	default:
+		return "", fmt.Errorf("不支持的配置格式: %s，支持 json, yaml", format)
+	}
+}
+
+// readConfigSource 从标准输入或 URL 读取并解析配置，返回内容摘要用于判断是否需要重新加载。
+// format 为空时依次根据 Content-Type、URL 扩展名和内容推断格式
+func readConfigSource(configPath, format string, config *Config) (string, error) {
+	var data []byte
+	var contentType string
+	var err error
+	if isStdinConfig(configPath) {
+		data, err = io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("从标准输入读取配置失败: %v", err)
+		}
+	} else {
+		data, contentType, err = fetchConfig(configPath)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if format == "" {
+		format = detectConfigFormat(configPath, contentType, data)
+	}
+	if err := parseConfig(data, format, displayConfigPath(configPath), config); err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// fetchConfig 通过 HTTP(S) 获取配置内容
+func fetchConfig(configURL string) ([]byte, string, error) {
+	client := &http.Client{Timeout: configFetchTimeout}
+	resp, err := client.Get(configURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("获取配置失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("获取配置失败: %s 返回 HTTP %d", displayConfigPath(configURL), resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("读取配置内容失败: %v", err)
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// detectConfigFormat 推断标准输入或 URL 配置的格式
+func detectConfigFormat(configPath, contentType string, data []byte) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch {
+		case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+			return "json"
+		case strings.Contains(mediaType, "yaml"):
+			return "yaml"
+		}
+	}
+
+	if u, err := url.Parse(configPath); err == nil && isURLConfig(configPath) {
+		switch strings.ToLower(path.Ext(u.Path)) {
+		case ".json":
+			return "json"
+		case ".yaml", ".yml":
+			return "yaml"
+		}
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return "json"
+	}
+	return "yaml"
+}
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	stateFileName       = "state.json"           // 状态目录中记录分离进程的文件
+	outputPollInterval  = 200 * time.Millisecond // 读取输出文件新内容的间隔
+	adoptedPollInterval = time.Second            // 检查接管进程存活的间隔
+
+	defaultDetachOutputMaxSize = 64 << 20 // 未配置 detach_output_max_size 时输出文件的字节数上限
+)
+
+// DetachedProcess keeper 分离退出时记录的进程信息，下次启动时据此重新接管
+type DetachedProcess struct {
+	PID          int       `json:"pid"`
+	StartTime    time.Time `json:"start_time"`
+	CommandLine  []string  `json:"command_line"` // 记录时 /proc 中的命令行，用于识别 PID 是否被复用
+	Restarts     int       `json:"restarts"`
+	StdoutOffset int64     `json:"stdout_offset"` // 已读取的输出文件位置，接管后从此处继续
+	StderrOffset int64     `json:"stderr_offset"`
+}
+
+// stateDir 返回分离模式的状态目录，默认为配置文件所在目录下的 .keeper
+func (pm *ProcessManager) stateDir() string {
+	if pm.config != nil && pm.config.Server.StateDir != "" {
+		return pm.config.Server.StateDir
+	}
+	return filepath.Join(configDir(pm.configPath), ".keeper")
+}
+
+// detachOutputMaxSize 返回分离模式输出文件的字节数上限
+func detachOutputMaxSize(server ServerConfig) int64 {
+	if server.DetachOutputMaxSize > 0 {
+		return server.DetachOutputMaxSize
+	}
+	return defaultDetachOutputMaxSize
+}
+
+// outputPaths 返回分离模式下进程标准输出和标准错误文件的路径
+func (pm *ProcessManager) outputPaths(name string) (string, string) {
+	base := filepath.Join(pm.stateDir(), url.PathEscape(name))
+	return base + ".stdout.log", base + ".stderr.log"
+}
+
+// redirectOutput 在分离模式下把子进程的输出直接写入文件（启动时清空），keeper 退出后输出不会因管道关闭而中断，
+// 返回的文件在进程启动后由调用者关闭
+func (pm *ProcessManager) redirectOutput(name string, cmd *exec.Cmd) ([]*os.File, error) {
+	if err := os.MkdirAll(pm.stateDir(), 0755); err != nil {
+		return nil, fmt.Errorf("创建状态目录失败: %v", err)
+	}
+
+	var files []*os.File
+	stdoutPath, stderrPath := pm.outputPaths(name)
+	for _, path := range []string{stdoutPath, stderrPath} {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|os.O_APPEND, 0644)
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+			return nil, fmt.Errorf("打开输出文件失败: %v", err)
+		}
+		files = append(files, file)
+	}
+
+	cmd.Stdout = files[0]
+	cmd.Stderr = files[1]
+	return files, nil
+}
+
+// tailOutputs 从指定位置开始读取进程的标准输出和标准错误文件并转发到日志，任一文件打开失败时返回 nil
+func (pm *ProcessManager) tailOutputs(name string, stdout, stderr *logWriter, stdoutOffset, stderrOffset int64) []*outputTail {
+	stdoutPath, stderrPath := pm.outputPaths(name)
+	var tails []*outputTail
+	for _, target := range []struct {
+		path   string
+		offset int64
+		writer *logWriter
+	}{
+		{stdoutPath, stdoutOffset, stdout},
+		{stderrPath, stderrOffset, stderr},
+	} {
+		tail, err := startOutputTail(target.path, target.offset, target.writer, detachOutputMaxSize(pm.config.Server))
+		if err != nil {
+			for _, t := range tails {
+				t.Close()
+			}
+			pm.addLog(name, fmt.Sprintf("WARNING: 读取输出文件失败: %v", err))
+			logWarnf("进程 %s 读取输出文件失败: %v", name, err)
+			return nil
+		}
+		tails = append(tails, tail)
+	}
+	return tails
+}
+
+// truncateDetachOutputs 清空分离模式的输出文件：进程运行中时由读取协程读完当前内容后清空，否则直接清空
+func (pm *ProcessManager) truncateDetachOutputs(name string, tails []*outputTail) error {
+	if tails != nil {
+		for _, tail := range tails {
+			tail.clear.Store(true)
+		}
+		return nil
+	}
+	stdoutPath, stderrPath := pm.outputPaths(name)
+	for _, path := range []string{stdoutPath, stderrPath} {
+		if err := os.Truncate(path, 0); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// outputTail 持续读取子进程直接写入的输出文件，转发到 logWriter
+type outputTail struct {
+	file    *os.File
+	writer  *logWriter
+	offset  atomic.Int64 // 已读取的字节位置
+	maxSize int64        // 读完的内容超过该字节数后清空文件，0 表示不清空
+	clear   atomic.Bool  // 请求在读完当前内容后清空文件（清空日志时设置）
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// startOutputTail 打开输出文件并从 offset 开始读取
+func startOutputTail(path string, offset int64, writer *logWriter, maxSize int64) (*outputTail, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	tail := &outputTail{
+		file:    file,
+		writer:  writer,
+		maxSize: maxSize,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	tail.offset.Store(offset)
+	go tail.run()
+	return tail, nil
+}
+
+func (t *outputTail) run() {
+	defer close(t.done)
+	defer t.file.Close()
+
+	buf := make([]byte, 32*1024)
+	stopping := false
+	for {
+		n, _ := t.file.Read(buf)
+		if n > 0 {
+			t.writer.Write(buf[:n])
+			t.offset.Add(int64(n))
+			continue
+		}
+
+		if t.clear.Swap(false) || (t.maxSize > 0 && t.offset.Load() >= t.maxSize) {
+			t.truncate()
+		}
+
+		// 收到停止通知后再读一轮，确保进程退出前写入的内容都已转发
+		if stopping {
+			t.writer.Flush()
+			return
+		}
+
+		select {
+		case <-t.stop:
+			stopping = true
+		case <-time.After(outputPollInterval):
+		}
+	}
+}
+
+// truncate 清空已全部读取的输出文件，从头继续读取。子进程以 O_APPEND 打开文件，之后的输出从文件开头写入；
+// 与 logrotate 的 copytruncate 一样，读到末尾和清空之间写入的少量内容会丢失。清空失败时不再尝试
+func (t *outputTail) truncate() {
+	if err := os.Truncate(t.file.Name(), 0); err != nil {
+		logWarnf("清空输出文件 %s 失败: %v", t.file.Name(), err)
+		t.maxSize = 0
+		return
+	}
+	t.file.Seek(0, io.SeekStart)
+	t.offset.Store(0)
+}
+
+// Close 读取剩余输出后停止，在进程退出后调用
+func (t *outputTail) Close() {
+	close(t.stop)
+	<-t.done
+}
+
+// Shutdown keeper 收到退出信号时处理子进程：启用 detach_on_exit 时分离，否则并发停止所有运行中的进程
+// （按各自的 stop_sequence 等待退出），避免 keeper 退出后遗留孤儿进程，下次启动时重复运行
+func (pm *ProcessManager) Shutdown() {
+	pm.mutex.RLock()
+	detach := pm.config != nil && pm.config.Server.DetachOnExit
+	names := make([]string, 0, len(pm.commands))
+	for name := range pm.commands {
+		names = append(names, name)
+	}
+	pm.mutex.RUnlock()
+
+	if detach {
+		if err := pm.DetachProcesses(); err != nil {
+			logErrorf("分离进程失败: %v", err)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := pm.StopProcess(name); err != nil && !errors.Is(err, ErrProcessNotRunning) {
+				logErrorf("退出时停止进程 %s 失败: %v", name, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// DetachProcesses 启用 detach_on_exit 时记录正在运行的进程而不停止它们，供下次启动时接管
+func (pm *ProcessManager) DetachProcesses() error {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	if pm.config == nil || !pm.config.Server.DetachOnExit {
+		return nil
+	}
+
+	detached := make(map[string]DetachedProcess)
+	for name, procInfo := range pm.commands {
+		status, exists := pm.processes[name]
+		if !exists || status.PID == 0 {
+			continue
+		}
+
+		// 启用 detach_on_exit 之前启动的进程输出仍经过管道，keeper 退出后写输出会收到 SIGPIPE
+		if procInfo.Outputs == nil {
+			logWarnf("进程 %s 的输出仍通过管道转发，keeper 退出后可能随之终止", name)
+		}
+
+		record := DetachedProcess{
+			PID:         status.PID,
+			StartTime:   status.StartTime,
+			CommandLine: readCommandLine(status.PID),
+			Restarts:    status.Restarts,
+		}
+		if procInfo.Outputs != nil {
+			record.StdoutOffset = procInfo.Outputs[0].offset.Load()
+			record.StderrOffset = procInfo.Outputs[1].offset.Load()
+		}
+		detached[name] = record
+	}
+
+	data, err := json.MarshalIndent(detached, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化状态失败: %v", err)
+	}
+	if err := os.MkdirAll(pm.stateDir(), 0755); err != nil {
+		return fmt.Errorf("创建状态目录失败: %v", err)
+	}
+	path := filepath.Join(pm.stateDir(), stateFileName)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("写入状态文件失败: %v", err)
+	}
+
+	logInfof("已分离 %d 个运行中的进程，状态保存到 %s", len(detached), path)
+	return nil
+}
+
+// AdoptProcesses 读取上次分离退出时保存的状态，接管仍在运行的进程
+func (pm *ProcessManager) AdoptProcesses() {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	if pm.config == nil || !pm.config.Server.DetachOnExit {
+		return
+	}
+
+	path := filepath.Join(pm.stateDir(), stateFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logWarnf("读取状态文件失败: %v", err)
+		}
+		return
+	}
+
+	// 状态文件只使用一次，避免之后误接管 PID 被复用的进程
+	os.Remove(path)
+
+	var detached map[string]DetachedProcess
+	if err := json.Unmarshal(data, &detached); err != nil {
+		logWarnf("解析状态文件 %s 失败: %v", path, err)
+		return
+	}
+
+	for name, record := range detached {
+		status, exists := pm.processes[name]
+		if !exists {
+			logWarnf("进程 %s 已不在配置中，不接管 PID %d", name, record.PID)
+			continue
+		}
+		if !processAlive(record.PID) {
+			pm.addLog(name, fmt.Sprintf("INFO: keeper 重启期间进程 %d 已退出", record.PID))
+			logInfof("进程 %s 在 keeper 重启期间已退出", name)
+			continue
+		}
+		if record.CommandLine != nil && !slices.Equal(readCommandLine(record.PID), record.CommandLine) {
+			logWarnf("PID %d 的命令行与记录不符，可能已被复用，不接管进程 %s", record.PID, name)
+			continue
+		}
+
+		pm.addLog(name, fmt.Sprintf("INFO: 上次分离的进程 %d 仍在运行", record.PID))
+		pm.adopt(name, status, record)
+	}
+}
+
+// adopt 接管上次分离的进程，调用者需持有锁
+func (pm *ProcessManager) adopt(name string, status *ProcessStatus, record DetachedProcess) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pid := record.PID
+	config := status.Config
+	ownGroup := ownsProcessGroup(config.ProcessGroup)
+	procInfo := &ProcessInfo{
+		// 接管的进程不是 keeper 的子进程，停止时直接向其进程组发送信号
+		Cancel: func() {
+			cancel()
+			signalProcess(pid, stopSignal(config), ownGroup)
+		},
+		Context:    ctx,
+		Done:       make(chan struct{}),
+		Cleaned:    make(chan struct{}),
+		AdoptedPID: pid,
+		OwnGroup:   ownGroup,
+	}
+
+	filter := compilePattern(config.LogFilter)
+	secrets := secretValues(config.Environment, effectiveSecretKeys(pm.config.Server))
+	procInfo.Pump = pm.newLogPump(name)
+	maxLine := maxLineLength(pm.config.Server)
+	stdout := &logWriter{name: name, pm: pm, isStdout: true, filter: filter, secrets: secrets, pump: procInfo.Pump, maxLine: maxLine, counter: &status.counters.stdout}
+	stderr := &logWriter{name: name, pm: pm, isStdout: false, filter: filter, secrets: secrets, pump: procInfo.Pump, maxLine: maxLine, counter: &status.counters.stderr}
+	// stdout_file、stderr_file 打开失败不影响接管，只是不再写入这些文件
+	resolved, err := resolvePlaceholders(config, pm.configPath)
+	if err == nil {
+		procInfo.Files, err = pm.openOutputFiles(resolved, true)
+		procInfo.Files.attach(stdout, stderr)
+	}
+	if err != nil {
+		pm.addLog(name, fmt.Sprintf("WARNING: %v", err))
+		logWarnf("进程 %s %v", name, err)
+	}
+	// 未启用 detach_on_exit 时（接管 keeper 异常退出遗留的进程）输出经过的管道已随 keeper 关闭，无法继续读取
+	if pm.config.Server.DetachOnExit {
+		procInfo.Outputs = pm.tailOutputs(name, stdout, stderr, record.StdoutOffset, record.StderrOffset)
+	}
+
+	pm.commands[name] = procInfo
+	status.PID = pid
+	status.Status = "running"
+	status.StartTime = record.StartTime
+	status.Restarts = record.Restarts
+	if record.CommandLine != nil {
+		status.CommandLine = record.CommandLine
+	}
+
+	pm.addLog(name, fmt.Sprintf("INFO: 已接管运行中的进程，PID: %d", pid))
+	logInfof("已接管进程 %s，PID: %d", name, pid)
+
+	if status.Config.Alert.enabled() {
+		go pm.monitorResources(name, procInfo, copyProcessConfig(status.Config))
+	}
+	go pm.monitorProcess(name)
+}
+
+// monitorAdopted 轮询接管进程的存活状态，直到其退出或被主动停止
+func (pm *ProcessManager) monitorAdopted(procInfo *ProcessInfo) error {
+	pid := procInfo.AdoptedPID
+	ticker := time.NewTicker(adoptedPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-procInfo.Context.Done():
+			for processAlive(pid) {
+				time.Sleep(pidFilePollInterval)
+			}
+			return procInfo.Context.Err()
+		case <-ticker.C:
+			if !processAlive(pid) {
+				return fmt.Errorf("接管的进程 %d 已退出", pid)
+			}
+		}
+	}
+}
+
+// signalProcessGroup 向以 pid 为组长的进程组发送信号，进程不是组长时只发给该进程
+func signalProcessGroup(pid int, sig syscall.Signal) error {
+	err := syscall.Kill(-pid, sig)
+	if err == syscall.ESRCH {
+		err = syscall.Kill(pid, sig)
+	}
+	return err
+}
+
+// readCommandLine 从 /proc 读取进程的命令行，读取失败时返回 nil
+func readCommandLine(pid int) []string {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\x00"), "\x00")
+}
+
+// pid 返回进程信息对应的 PID，进程尚未启动时返回 0
+func (p *ProcessInfo) pid() int {
+	if p.Cmd == nil {
+		return p.AdoptedPID
+	}
+	if p.Cmd.Process == nil {
+		return 0
+	}
+	return p.Cmd.Process.Pid
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestOutputTailTruncatesLargeFile(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: svc
+    command: fake-sleep
+`)
+	path := filepath.Join(t.TempDir(), "svc.stdout.log")
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	pump := pm.newLogPump("svc")
+	lw := &logWriter{name: "svc", pm: pm, isStdout: true, pump: pump}
+	tail, err := startOutputTail(path, 0, lw, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 读完超过上限的内容后清空文件，子进程以 O_APPEND 写入的后续输出从头开始，仍能被读取
+	out.Write([]byte("hello world\n"))
+	waitFor(t, "清空输出文件", func() bool {
+		info, err := os.Stat(path)
+		return err == nil && info.Size() == 0 && tail.offset.Load() == 0
+	})
+	out.Write([]byte("again\n"))
+	tail.Close()
+	pump.Close()
+
+	want := []string{"STDOUT: hello world", "STDOUT: again"}
+	if got := outputOf(t, pm, "svc"); !slices.Equal(got, want) {
+		t.Fatalf("日志行为 %q，期望 %q", got, want)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "again\n" {
+		t.Fatalf("输出文件内容为 %q", data)
+	}
+}
+
+func TestOutputTailClearRequest(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: svc
+    command: fake-sleep
+`)
+	path := filepath.Join(t.TempDir(), "svc.stdout.log")
+	if err := os.WriteFile(path, []byte("before\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pump := pm.newLogPump("svc")
+	lw := &logWriter{name: "svc", pm: pm, isStdout: true, pump: pump}
+	tail, err := startOutputTail(path, 0, lw, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pump.Close()
+	defer tail.Close()
+
+	// 清空日志时通过 truncateDetachOutputs 请求读取协程清空文件
+	waitFor(t, "读取输出文件", func() bool { return tail.offset.Load() == 7 })
+	if err := pm.truncateDetachOutputs("svc", []*outputTail{tail}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "清空输出文件", func() bool {
+		info, err := os.Stat(path)
+		return err == nil && info.Size() == 0 && tail.offset.Load() == 0
+	})
+}
+
+func TestShutdownStopsProcessesWithoutDetach(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: api
+    command: fake-sleep
+    enabled: true
+  - name: worker
+    command: fake-sleep
+    enabled: true
+`)
+	var pids []int
+	for _, name := range []string{"api", "worker"} {
+		if err := pm.StartProcess(name); err != nil {
+			t.Fatalf("StartProcess(%s): %v", name, err)
+		}
+		pids = append(pids, processState(t, pm, name).PID)
+	}
+
+	pm.Shutdown()
+
+	for i, name := range []string{"api", "worker"} {
+		if status := processState(t, pm, name); status.Status != "stopped" {
+			t.Errorf("进程 %s 状态为 %s，期望 stopped", name, status.Status)
+		}
+		if processAlive(pids[i]) {
+			t.Errorf("进程 %s（PID %d）在 keeper 退出后仍在运行", name, pids[i])
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/goccy/go-yaml/token"
+)
+
+// envReferencePattern 匹配 ${VAR}、${VAR:-default} 以及转义形式 $${...}
+var envReferencePattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// envExpander 使用 keeper 自身的环境变量替换 ${VAR} 引用：
+// 变量未设置时使用 ${VAR:-default} 中的默认值，没有默认值则记录到 missing；$${VAR} 保留为字面量 ${VAR}
+type envExpander struct {
+	escape  func(string) string // 替换的值写入前的转义，为空时原样写入
+	missing []string
+}
+
+// expand 替换 s 中的引用
+func (e *envExpander) expand(s string) string {
+	return envReferencePattern.ReplaceAllStringFunc(s, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		groups := envReferencePattern.FindStringSubmatch(match)
+		name := groups[1]
+		value, ok := os.LookupEnv(name)
+		if !ok && groups[2] == "" {
+			if !slices.Contains(e.missing, name) {
+				e.missing = append(e.missing, name)
+			}
+			return match
+		}
+		if !ok {
+			value = groups[3]
+		}
+		if e.escape != nil {
+			value = e.escape(value)
+		}
+		return value
+	})
+}
+
+// err 有未设置的变量时返回错误
+func (e *envExpander) err() error {
+	if len(e.missing) > 0 {
+		return fmt.Errorf("环境变量未设置: %s", strings.Join(e.missing, ", "))
+	}
+	return nil
+}
+
+// expandJSONEnv 替换 JSON 配置中的引用。JSON 没有注释，替换的值按 JSON 字符串转义，
+// 值中的引号、反斜杠和换行不会破坏配置结构；写在字符串之外的数字引用不受影响
+func expandJSONEnv(data []byte) ([]byte, error) {
+	e := &envExpander{escape: func(value string) string {
+		quoted, _ := json.Marshal(value)
+		return string(quoted[1 : len(quoted)-1])
+	}}
+	result := e.expand(string(data))
+	if err := e.err(); err != nil {
+		return nil, err
+	}
+	return []byte(result), nil
+}
+
+// decodeYAMLConfig 解析 YAML 配置，只替换标量值中的引用后再解码到 config：注释中的引用不会被替换，
+// 替换的值也不会改变配置结构，无需转义。未加引号的标量按替换后的内容重新判断类型（数字、布尔值），
+// 与直接写在配置中一致。返回的 expandErr 为未设置的环境变量，parseErr 为解析错误
+func decodeYAMLConfig(data []byte, config *Config) (expandErr, parseErr error) {
+	file, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Docs) == 0 || file.Docs[0].Body == nil {
+		return nil, nil
+	}
+
+	e := &envExpander{}
+	body := e.expandNode(file.Docs[0].Body)
+	if err := e.err(); err != nil {
+		return err, nil
+	}
+	return nil, yaml.NodeToValue(body, config)
+}
+
+// expandNode 替换节点下所有标量值中的引用（不包括映射的键），返回替换后的节点
+func (e *envExpander) expandNode(node ast.Node) ast.Node {
+	switch n := node.(type) {
+	case *ast.MappingNode:
+		for _, value := range n.Values {
+			value.Value = e.expandNode(value.Value)
+		}
+	case *ast.MappingValueNode:
+		n.Value = e.expandNode(n.Value)
+	case *ast.SequenceNode:
+		for i, value := range n.Values {
+			n.Values[i] = e.expandNode(value)
+		}
+	case *ast.AnchorNode:
+		n.Value = e.expandNode(n.Value)
+	case *ast.TagNode:
+		n.Value = e.expandNode(n.Value)
+	case *ast.LiteralNode:
+		n.Value.Value = e.expand(n.Value.Value)
+	case *ast.StringNode:
+		value := e.expand(n.Value)
+		if value == n.Value {
+			return n
+		}
+		n.Value = value
+		if n.Token.Type == token.StringType {
+			if typed := plainScalar(value); typed != nil {
+				return typed
+			}
+		}
+	}
+	return node
+}
+
+// plainScalar 把未加引号的标量按 YAML 规则解析，是数字、布尔值或空值时返回对应的节点，否则返回 nil（仍作为字符串）
+func plainScalar(value string) ast.Node {
+	if strings.ContainsAny(value, "\n") {
+		return nil
+	}
+	file, err := parser.ParseBytes([]byte(value), 0)
+	if err != nil || len(file.Docs) == 0 {
+		return nil
+	}
+	switch body := file.Docs[0].Body.(type) {
+	case *ast.IntegerNode, *ast.FloatNode, *ast.BoolNode, *ast.NullNode, *ast.InfinityNode, *ast.NanNode:
+		return body
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestYAMLEnvSubstitution(t *testing.T) {
+	t.Setenv("KEEPER_TEST_PASSWORD", `p@ss: "word" # not a comment`)
+	t.Setenv("KEEPER_TEST_DELAY", "7")
+	t.Setenv("KEEPER_TEST_ENABLED", "true")
+
+	var config Config
+	err := parseConfig([]byte(`
+# 注释中的 ${KEEPER_TEST_UNSET} 不会被替换，也不要求设置
+processes:
+  - name: api
+    command: server
+    enabled: ${KEEPER_TEST_ENABLED}
+    restart_delay: ${KEEPER_TEST_DELAY} # ${KEEPER_TEST_UNSET}
+    args: ["--password", "${KEEPER_TEST_PASSWORD}", '${KEEPER_TEST_PASSWORD}']
+    environment:
+      PLAIN: ${KEEPER_TEST_PASSWORD}
+      DELAY: "${KEEPER_TEST_DELAY}"
+      LEVEL: ${KEEPER_TEST_UNSET:-info}
+      LITERAL: $${KEEPER_TEST_DELAY}
+`), "yaml", "keeper.yaml", &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	password := `p@ss: "word" # not a comment`
+	process := config.Processes[0]
+	if !process.Enabled || process.RestartDelay != 7 {
+		t.Fatalf("enabled %v，restart_delay %d", process.Enabled, process.RestartDelay)
+	}
+	if len(process.Args) != 3 || process.Args[1] != password || process.Args[2] != password {
+		t.Fatalf("args 为 %q", process.Args)
+	}
+	want := map[string]string{"PLAIN": password, "DELAY": "7", "LEVEL": "info", "LITERAL": "${KEEPER_TEST_DELAY}"}
+	for key, value := range want {
+		if process.Environment[key] != value {
+			t.Errorf("%s 为 %q，期望 %q", key, process.Environment[key], value)
+		}
+	}
+}
+
+func TestYAMLEnvSubstitutionMissing(t *testing.T) {
+	var config Config
+	err := parseConfig([]byte(`
+processes:
+  - name: api
+    command: ${KEEPER_TEST_UNSET}
+`), "yaml", "keeper.yaml", &config)
+	if err == nil || !strings.Contains(err.Error(), "环境变量未设置: KEEPER_TEST_UNSET") {
+		t.Fatalf("错误为 %v", err)
+	}
+}
+
+func TestJSONEnvSubstitutionEscapes(t *testing.T) {
+	t.Setenv("KEEPER_TEST_PASSWORD", "a\"b\\c\nd")
+	t.Setenv("KEEPER_TEST_DELAY", "7")
+
+	var config Config
+	err := parseConfig([]byte(`{"processes": [{"name": "api", "command": "server",
+		"restart_delay": ${KEEPER_TEST_DELAY},
+		"environment": {"PASSWORD": "${KEEPER_TEST_PASSWORD}"}}]}`), "json", "keeper.json", &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	process := config.Processes[0]
+	if process.RestartDelay != 7 || process.Environment["PASSWORD"] != "a\"b\\c\nd" {
+		t.Fatalf("restart_delay %d，PASSWORD %q", process.RestartDelay, process.Environment["PASSWORD"])
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// 进程类型
+const (
+	ProcessTypeSimple  = "simple"  // 直接监控启动的子进程
+	ProcessTypeForking = "forking" // 启动命令会 fork 后退出，监控 PID 文件中的守护进程
+	ProcessTypeOneshot = "oneshot" // 运行一次直至结束，退出后不再重启
+)
+
+const (
+	pidFileTimeout      = 10 * time.Second       // 等待 PID 文件出现的最长时间
+	pidFilePollInterval = 200 * time.Millisecond // 读取 PID 文件的间隔
+	daemonPollInterval  = time.Second            // 检查守护进程存活的间隔
+	launcherWaitDelay   = 2 * time.Second        // 启动命令退出后最多等待其输出管道关闭的时间（守护进程可能继承了管道）
+	daemonKillWait      = 5 * time.Second        // 强制杀死守护进程后等待其消失的最长时间
+)
+
+// monitorForking 监控 forking 类型进程的守护进程，直到其退出或被主动停止
+func (pm *ProcessManager) monitorForking(name string, procInfo *ProcessInfo, config ProcessConfig) error {
+	pid, err := waitForPIDFile(procInfo.Context, config.PIDFile)
+	if err != nil {
+		if procInfo.Context.Err() != nil {
+			return procInfo.Context.Err()
+		}
+		return err
+	}
+
+	procInfo.DaemonPID.Store(int64(pid))
+
+	pm.mutex.Lock()
+	if status, exists := pm.processes[name]; exists && pm.commands[name] == procInfo {
+		status.PID = pid
+		pm.addLog(name, fmt.Sprintf("INFO: 从 PID 文件 %s 读取到守护进程 PID: %d", config.PIDFile, pid))
+		pm.recordRunning()
+	}
+	pm.mutex.Unlock()
+	logInfof("进程 %s 的守护进程 PID: %d", name, pid)
+
+	ticker := time.NewTicker(daemonPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-procInfo.Context.Done():
+			// 主动停止：StopProcess 负责发送信号，这里等待守护进程退出。
+			// 超过 stop_sequence 的全部等待时间仍未退出（例如没有经过 StopProcess 升级信号）时强制杀死
+			if !waitProcessExit(pid, stopWaitTotal(stopSteps(config))) {
+				logWarnf("进程 %s 的守护进程 %d 未在停止等待时间内退出，强制终止", name, pid)
+				syscall.Kill(pid, syscall.SIGKILL)
+				waitProcessExit(pid, daemonKillWait)
+			}
+			return procInfo.Context.Err()
+		case <-ticker.C:
+			if !processAlive(pid) {
+				return fmt.Errorf("守护进程 %d 已退出", pid)
+			}
+		}
+	}
+}
+
+// waitProcessExit 等待进程退出，超时返回 false
+func waitProcessExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(pidFilePollInterval)
+	}
+	return true
+}
+
+// waitForPIDFile 等待并读取 PID 文件，直到超时或上下文被取消
+func waitForPIDFile(ctx context.Context, pidFile string) (int, error) {
+	deadline := time.Now().Add(pidFileTimeout)
+	var lastErr error
+
+	for {
+		pid, err := readPIDFile(pidFile)
+		if err == nil && processAlive(pid) {
+			return pid, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("PID 文件 %s 中的进程 %d 不存在", pidFile, pid)
+		}
+		lastErr = err
+
+		if time.Now().After(deadline) {
+			return 0, fmt.Errorf("等待 PID 文件超时: %v", lastErr)
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(pidFilePollInterval):
+		}
+	}
+}
+
+// readPIDFile 读取 PID 文件
+func readPIDFile(pidFile string) (int, error) {
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		return 0, fmt.Errorf("读取 PID 文件失败: %v", err)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("PID 文件 %s 内容无效", pidFile)
+	}
+	return pid, nil
+}
+
+// processAlive 通过信号 0 检查进程是否存在。已退出但尚未被回收的僵尸进程视为不存在：
+// 守护进程的父进程退出后由 init 负责回收，容器中的 1 号进程不一定会及时回收
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	if err != nil && err != syscall.EPERM {
+		return false
+	}
+	return !processZombie(pid)
+}
+
+// processZombie 通过 /proc/<pid>/stat 中的状态判断进程是否为僵尸进程，无法读取时视为不是
+func processZombie(pid int) bool {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	// 进程名可能包含空格和括号，状态是最后一个右括号之后的第一个字段
+	stat := string(data)
+	end := strings.LastIndexByte(stat, ')')
+	return end >= 0 && strings.HasPrefix(strings.TrimSpace(stat[end+1:]), "Z")
+}
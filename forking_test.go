@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// forkingConfig 返回 forking 类型进程的配置：启动命令在后台启动 daemon（继承输出管道）并写入 PID 文件后退出
+func forkingConfig(t *testing.T, daemon string) (config, pidFile string) {
+	pidFile = filepath.Join(t.TempDir(), "daemon.pid")
+	script := "(" + daemon + ") & echo $! > " + pidFile
+	return `
+processes:
+  - name: daemon
+    command: sh
+    args: ["-c", "` + strings.ReplaceAll(script, `"`, `\"`) + `"]
+    enabled: true
+    type: forking
+    pidfile: ` + pidFile + `
+    stop_sequence:
+      - signal: SIGTERM
+        wait: 1
+`, pidFile
+}
+
+func TestForkingDaemonHoldingPipes(t *testing.T) {
+	config, pidFile := forkingConfig(t, "exec sleep 100")
+	pm, _ := newTestManager(t, config)
+
+	if err := pm.StartProcess("daemon"); err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+	// 守护进程持有启动命令的输出管道，启动命令退出后仍应读取 PID 文件并开始监控守护进程
+	waitFor(t, "读取守护进程 PID", func() bool {
+		pid, err := readPIDFile(pidFile)
+		return err == nil && processState(t, pm, "daemon").PID == pid
+	})
+	if status := processState(t, pm, "daemon"); status.Status != "running" {
+		t.Fatalf("状态为 %s", status.Status)
+	}
+}
+
+func TestForkingCancelKillsStubbornDaemon(t *testing.T) {
+	config, pidFile := forkingConfig(t, `trap "" TERM; exec sleep 100`)
+	pm, _ := newTestManager(t, config)
+
+	if err := pm.StartProcess("daemon"); err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+	var pid int
+	waitFor(t, "读取守护进程 PID", func() bool {
+		var err error
+		pid, err = readPIDFile(pidFile)
+		return err == nil && processState(t, pm, "daemon").PID == pid
+	})
+
+	// 不经过 StopProcess 直接取消：守护进程忽略 SIGTERM，超过 stop_sequence 的等待时间后应被强制杀死
+	pm.mutex.RLock()
+	procInfo := pm.commands["daemon"]
+	pm.mutex.RUnlock()
+	procInfo.Cancel()
+
+	select {
+	case <-procInfo.Done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("取消后监控没有结束")
+	}
+	if processAlive(pid) {
+		t.Fatalf("守护进程 %d 仍在运行", pid)
+	}
+}
@@ -2,4 +2,9 @@ module linker-keeper
 
 go 1.24.1
 
-require github.com/goccy/go-yaml v1.18.0
+require (
+	github.com/fsnotify/fsnotify v1.10.1
+	github.com/goccy/go-yaml v1.18.0
+)
+
+require golang.org/x/sys v0.13.0 // indirect
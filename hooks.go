@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// defaultHookTimeout pre_start、post_stop 钩子命令的默认超时时间
+const defaultHookTimeout = 30 * time.Second
+
+// hookTimeout 返回钩子命令的超时时间
+func hookTimeout(config ProcessConfig) time.Duration {
+	if config.HookTimeout > 0 {
+		return time.Duration(config.HookTimeout) * time.Second
+	}
+	return defaultHookTimeout
+}
+
+// runHook 通过 shell 执行钩子命令，使用进程配置的工作目录、环境变量和用户，
+// 返回合并后的标准输出和标准错误（按行拆分），超时后终止整个进程组
+func runHook(runner Runner, config ProcessConfig, command string) ([]string, error) {
+	timeout := hookTimeout(config)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	shell := config.ShellPath
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	hook := config
+	hook.Command = shell
+	hook.Args = []string{"-c", command}
+	hook.TrimEmptyArgs = false
+
+	var cmd *exec.Cmd
+	if config.User != "" {
+		cmd = runner.CommandContext(ctx, "sudo", buildSudoArgs(hook)...)
+	} else {
+		cmd = runner.CommandContext(ctx, shell, hook.Args...)
+	}
+	if config.WorkDir != "" {
+		cmd.Dir = config.WorkDir
+	}
+	if len(config.Environment) > 0 {
+		env := os.Environ()
+		for key, value := range config.Environment {
+			env = append(env, fmt.Sprintf("%s=%s", key, value))
+		}
+		cmd.Env = env
+	}
+
+	// 超时后杀死整个进程组，后台子进程仍持有输出管道时最多再等待 1 秒
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+
+	var lines []string
+	for _, line := range strings.Split(output.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return lines, fmt.Errorf("执行超时 (%v)", timeout)
+	case errors.As(err, &exitErr):
+		return lines, fmt.Errorf("退出码 %d", exitErr.ExitCode())
+	case err != nil:
+		return lines, err
+	}
+	return lines, nil
+}
+
+// hookSecrets 返回钩子命令和输出中需要抹除的敏感值，与进程输出使用相同的规则。调用方需持有 mutex
+func (pm *ProcessManager) hookSecrets(config ProcessConfig) []string {
+	keys := defaultSecretKeys
+	if pm.config != nil {
+		keys = effectiveSecretKeys(pm.config.Server)
+	}
+	return secretValues(config.Environment, keys)
+}
+
+// runPreStart 执行 pre_start 前置检查，失败时进程不启动，状态设为 error。
+// 调用方需持有 mutex 并已将状态设为 starting，命令执行期间会暂时释放锁
+func (pm *ProcessManager) runPreStart(name string, status *ProcessStatus, config ProcessConfig) error {
+	secrets := pm.hookSecrets(config)
+	pm.addLog(name, fmt.Sprintf("INFO: 执行 pre_start: %s", scrubSecrets(config.PreStart, secrets)))
+
+	pm.mutex.Unlock()
+	output, err := runHook(pm.runner, config, config.PreStart)
+	pm.mutex.Lock()
+
+	for _, line := range output {
+		pm.addLog(name, "PRE_START: "+scrubSecrets(line, secrets))
+	}
+
+	// 执行期间进程可能被从配置中移除
+	if pm.processes[name] != status {
+		return newProcessError(ErrProcessNotFound, "进程 %s 已从配置中移除", name)
+	}
+
+	if err != nil {
+		status.Status = "error"
+		status.LastError = fmt.Sprintf("前置条件检查失败: %v", err)
+		pm.addLog(name, "ERROR: "+status.LastError)
+		logWarnf("进程 %s 前置条件检查失败: %v", name, err)
+		return fmt.Errorf("进程 %s 前置条件检查失败: %v", name, err)
+	}
+	return nil
+}
+
+// runPostStop 进程退出后执行 post_stop 清理命令，输出记录到进程日志，失败只记录警告
+func (pm *ProcessManager) runPostStop(name string, config ProcessConfig) {
+	resolved, err := resolvePlaceholders(config, pm.configPath)
+	if err != nil {
+		pm.mutex.Lock()
+		pm.addLog(name, fmt.Sprintf("WARNING: post_stop 占位符替换失败: %v", err))
+		pm.mutex.Unlock()
+		return
+	}
+
+	pm.mutex.Lock()
+	secrets := pm.hookSecrets(resolved)
+	pm.addLog(name, fmt.Sprintf("INFO: 执行 post_stop: %s", scrubSecrets(resolved.PostStop, secrets)))
+	pm.mutex.Unlock()
+
+	output, err := runHook(pm.runner, resolved, resolved.PostStop)
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	for _, line := range output {
+		pm.addLog(name, "POST_STOP: "+scrubSecrets(line, secrets))
+	}
+	if err != nil {
+		pm.addLog(name, fmt.Sprintf("WARNING: post_stop 执行失败: %v", err))
+		logWarnf("进程 %s post_stop 执行失败: %v", name, err)
+	}
+}
+
+// runAlertCommand 执行 alert_command，进程名称和 env 中的告警信息通过环境变量传入，输出记录到进程日志
+func (pm *ProcessManager) runAlertCommand(name string, config ProcessConfig, env map[string]string) {
+	resolved, err := resolvePlaceholders(config, pm.configPath)
+	if err != nil {
+		pm.mutex.Lock()
+		pm.addLog(name, fmt.Sprintf("WARNING: alert_command 占位符替换失败: %v", err))
+		pm.mutex.Unlock()
+		return
+	}
+	resolved.Environment = maps.Clone(resolved.Environment)
+	if resolved.Environment == nil {
+		resolved.Environment = make(map[string]string)
+	}
+	maps.Copy(resolved.Environment, env)
+	resolved.Environment["KEEPER_PROCESS"] = name
+
+	pm.mutex.Lock()
+	secrets := pm.hookSecrets(resolved)
+	pm.addLog(name, fmt.Sprintf("INFO: 执行 alert_command: %s", scrubSecrets(resolved.AlertCommand, secrets)))
+	pm.mutex.Unlock()
+
+	output, err := runHook(pm.runner, resolved, resolved.AlertCommand)
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	for _, line := range output {
+		pm.addLog(name, "ALERT: "+scrubSecrets(line, secrets))
+	}
+	if err != nil {
+		pm.addLog(name, fmt.Sprintf("WARNING: alert_command 执行失败: %v", err))
+		logWarnf("进程 %s alert_command 执行失败: %v", name, err)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// logsContaining 返回进程日志中包含 substr 的行
+func logsContaining(t *testing.T, pm *ProcessManager, name, substr string) []string {
+	t.Helper()
+	var lines []string
+	for _, line := range processState(t, pm, name).Output {
+		if strings.Contains(line, substr) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestPreStartOutputIsScrubbed(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: svc
+    command: fake-sleep
+    enabled: true
+    pre_start: echo token=$API_TOKEN; echo token=hunter2
+    environment:
+      API_TOKEN: hunter2
+`)
+
+	if err := pm.StartProcess("svc"); err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+	if leaked := logsContaining(t, pm, "svc", "hunter2"); len(leaked) > 0 {
+		t.Fatalf("日志中出现了敏感值: %q", leaked)
+	}
+	if lines := logsContaining(t, pm, "svc", "PRE_START: token=***"); len(lines) != 2 {
+		t.Fatalf("pre_start 输出为 %q", logsContaining(t, pm, "svc", "PRE_START"))
+	}
+}
+
+func TestPostStopOutputIsScrubbed(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: svc
+    command: fake-sleep
+    enabled: true
+    post_stop: echo token=$API_TOKEN
+    environment:
+      API_TOKEN: hunter2
+`)
+
+	if err := pm.StartProcess("svc"); err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+	if err := pm.StopProcess("svc"); err != nil {
+		t.Fatalf("StopProcess: %v", err)
+	}
+	if leaked := logsContaining(t, pm, "svc", "hunter2"); len(leaked) > 0 {
+		t.Fatalf("日志中出现了敏感值: %q", leaked)
+	}
+	if lines := logsContaining(t, pm, "svc", "POST_STOP: token=***"); len(lines) != 1 {
+		t.Fatalf("post_stop 输出为 %q", logsContaining(t, pm, "svc", "POST_STOP"))
+	}
+}
+
+func TestAlertCommandOutputIsScrubbed(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: svc
+    command: fake-sleep
+    alert_command: test -n hunter2 && echo $KEEPER_PROCESS $API_TOKEN
+    environment:
+      API_TOKEN: hunter2
+`)
+
+	status := processState(t, pm, "svc")
+	pm.runAlertCommand("svc", status.Config, map[string]string{"KEEPER_ALERT": "test"})
+
+	if leaked := logsContaining(t, pm, "svc", "hunter2"); len(leaked) > 0 {
+		t.Fatalf("日志中出现了敏感值: %q", leaked)
+	}
+	if lines := logsContaining(t, pm, "svc", "ALERT: svc ***"); len(lines) != 1 {
+		t.Fatalf("alert_command 输出为 %q", logsContaining(t, pm, "svc", "ALERT"))
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// resolveIncludes 展开 include 中的通配符，相对路径基于主配置文件所在目录，结果按路径排序
+func resolveIncludes(configPath string, patterns []string) ([]string, error) {
+	baseDir := configDir(configPath)
+	seen := make(map[string]bool)
+	var files []string
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(baseDir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("include 模式无效 %s: %v", pattern, err)
+		}
+		for _, match := range matches {
+			if seen[match] || filepath.Clean(match) == filepath.Clean(configPath) {
+				continue
+			}
+			seen[match] = true
+			files = append(files, match)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// loadIncludedProcesses 读取被包含的配置文件，返回其中的进程配置
+func loadIncludedProcesses(files []string) ([]ProcessConfig, error) {
+	var processes []ProcessConfig
+	for _, file := range files {
+		var included Config
+		if err := readConfigFile(file, &included); err != nil {
+			return nil, err
+		}
+		if len(included.Include) > 0 {
+			return nil, fmt.Errorf("被包含的配置文件 %s 不支持嵌套 include", file)
+		}
+		processes = append(processes, included.Processes...)
+	}
+	return processes, nil
+}
+
+// configFingerprint 根据主配置文件和被包含文件的路径与修改时间生成指纹，
+// 用于判断配置是否需要重新加载（文件被修改、新增或删除都会改变指纹）
+func configFingerprint(files []string) (string, error) {
+	var parts []string
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", fmt.Errorf("无法获取配置文件信息: %v", err)
+		}
+		parts = append(parts, fmt.Sprintf("%s@%d", file, info.ModTime().UnixNano()))
+	}
+	return strings.Join(parts, ";"), nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// processLock lock_file 上持有的排他锁（flock），进程退出并执行完 post_stop 后释放
+type processLock struct {
+	path string
+	file *os.File
+}
+
+// acquireLock 以非阻塞方式对 lock_file 加排他锁，相对路径相对于配置文件所在目录。
+// 未配置时返回 nil；锁已被其他 keeper 或进程持有时返回 ErrProcessLocked
+func (pm *ProcessManager) acquireLock(config ProcessConfig) (*processLock, error) {
+	if config.LockFile == "" {
+		return nil, nil
+	}
+
+	path := config.LockFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(configDir(pm.configPath), path)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开 lock_file 失败: %v", err)
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		holder := lockHolder(file)
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			if holder != "" {
+				return nil, newProcessError(ErrProcessLocked, "进程 %s 已在其他位置运行（锁文件 %s 被 PID %s 持有）", config.Name, path, holder)
+			}
+			return nil, newProcessError(ErrProcessLocked, "进程 %s 已在其他位置运行（锁文件 %s 被占用）", config.Name, path)
+		}
+		return nil, fmt.Errorf("锁定 lock_file 失败: %v", err)
+	}
+	return &processLock{path: path, file: file}, nil
+}
+
+// lockHolder 返回锁文件中记录的持有者 PID，未记录时返回空字符串
+func lockHolder(file *os.File) string {
+	buf := make([]byte, 32)
+	n, _ := file.ReadAt(buf, 0)
+	pid := strings.TrimSpace(string(buf[:n]))
+	if _, err := strconv.Atoi(pid); err != nil {
+		return ""
+	}
+	return pid
+}
+
+// writePID 把进程 PID 写入锁文件，便于排查是谁持有锁
+func (l *processLock) writePID(pid int) {
+	if l == nil {
+		return
+	}
+	l.file.Truncate(0)
+	l.file.WriteAt([]byte(strconv.Itoa(pid)+"\n"), 0)
+}
+
+// Release 释放锁，nil 时不做任何操作。锁文件本身保留，删除会让等待同一路径的其他持有者锁住不同的文件
+func (l *processLock) Release() {
+	if l == nil {
+		return
+	}
+	l.file.Truncate(0)
+	l.file.Close()
+}
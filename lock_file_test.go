@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// openFiles 返回进程打开的文件路径
+func openFiles(t *testing.T, pid int) []string {
+	t.Helper()
+	dir := fmt.Sprintf("/proc/%d/fd", pid)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, entry := range entries {
+		if target, err := os.Readlink(filepath.Join(dir, entry.Name())); err == nil {
+			paths = append(paths, target)
+		}
+	}
+	return paths
+}
+
+func TestLockFileInheritedWithoutDetach(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: svc
+    command: fake-sleep
+    enabled: true
+    lock_file: svc.lock
+`)
+
+	if err := pm.StartProcess("svc"); err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+	// keeper 意外退出时进程仍持有锁，新的 keeper 不会再启动一份
+	path := filepath.Join(configDir(pm.configPath), "svc.lock")
+	status := processState(t, pm, "svc")
+	if files := openFiles(t, status.PID); !slices.Contains(files, path) {
+		t.Fatalf("进程没有继承锁文件 %s，打开的文件: %q", path, files)
+	}
+}
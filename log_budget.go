@@ -0,0 +1,64 @@
+package main
+
+import "container/heap"
+
+// outputHeap 按日志缓冲占用字节数排列的最大堆，超出 max_total_log_bytes 时从堆顶（占用最多的进程）淘汰，
+// 每次追加或淘汰一行只需 O(log n) 调整位置，不必遍历所有进程。由 logMutex 保护
+type outputHeap []*ProcessStatus
+
+func (h outputHeap) Len() int           { return len(h) }
+func (h outputHeap) Less(i, j int) bool { return h[i].outputBytes > h[j].outputBytes }
+
+func (h outputHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *outputHeap) Push(x any) {
+	status := x.(*ProcessStatus)
+	status.heapIndex = len(*h)
+	*h = append(*h, status)
+}
+
+func (h *outputHeap) Pop() any {
+	old := *h
+	status := old[len(old)-1]
+	old[len(old)-1] = nil
+	status.heapIndex = -1
+	*h = old[:len(old)-1]
+	return status
+}
+
+// trackOutput 开始统计进程的日志缓冲，调用方需持有 logMutex
+func (pm *ProcessManager) trackOutput(status *ProcessStatus) {
+	heap.Push(&pm.outputHeap, status)
+}
+
+// untrackOutput 停止统计进程的日志缓冲，调用方需持有 logMutex
+func (pm *ProcessManager) untrackOutput(status *ProcessStatus) {
+	if status.heapIndex >= 0 && status.heapIndex < len(pm.outputHeap) && pm.outputHeap[status.heapIndex] == status {
+		heap.Remove(&pm.outputHeap, status.heapIndex)
+	}
+}
+
+// resizeOutput 进程日志缓冲的字节数变化 delta，同步更新总字节数和堆中的位置，调用方需持有 logMutex
+func (pm *ProcessManager) resizeOutput(status *ProcessStatus, delta int) {
+	status.outputBytes += delta
+	pm.logBytes += delta
+	if status.heapIndex >= 0 && status.heapIndex < len(pm.outputHeap) && pm.outputHeap[status.heapIndex] == status {
+		heap.Fix(&pm.outputHeap, status.heapIndex)
+	}
+}
+
+// enforceLogBudget 超出总字节上限时，从占用最多的进程开始淘汰最早的日志，调用方需持有 logMutex
+func (pm *ProcessManager) enforceLogBudget() {
+	limit := pm.maxLogBytes
+	for limit > 0 && pm.logBytes > limit && len(pm.outputHeap) > 0 {
+		largest := pm.outputHeap[0]
+		if len(largest.Output) == 0 {
+			break
+		}
+		pm.dropOldestOutput(largest)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLogBudgetEvictsLargestProcess(t *testing.T) {
+	pm, _ := newTestManager(t, `
+server:
+  max_total_log_bytes: 100
+processes:
+  - name: noisy
+    command: fake-sleep
+  - name: quiet
+    command: fake-sleep
+  - name: idle
+    command: fake-sleep
+`)
+	line := strings.Repeat("x", 10)
+
+	pm.mutex.Lock()
+	pm.logMutex.Lock()
+	for range 3 {
+		pm.appendOutput(pm.processes["quiet"], line)
+	}
+	for range 20 {
+		pm.appendOutput(pm.processes["noisy"], line)
+	}
+	pm.logMutex.Unlock()
+	pm.mutex.Unlock()
+
+	// 超出上限时只淘汰占用最多的进程，quiet 的日志保留
+	if n := len(processState(t, pm, "quiet").Output); n != 3 {
+		t.Fatalf("quiet 保留了 %d 行，期望 3 行", n)
+	}
+	if n := len(processState(t, pm, "noisy").Output); n != 7 {
+		t.Fatalf("noisy 保留了 %d 行，期望 7 行", n)
+	}
+
+	// 删除进程后不再参与淘汰，释放的字节数从总数中扣除
+	pm.mutex.Lock()
+	pm.removeProcess("noisy")
+	pm.logMutex.Lock()
+	total, tracked := pm.logBytes, len(pm.outputHeap)
+	for i, status := range pm.outputHeap {
+		if status.heapIndex != i {
+			t.Errorf("%s 的 heapIndex 为 %d，实际位置 %d", status.Config.Name, status.heapIndex, i)
+		}
+	}
+	pm.logMutex.Unlock()
+	pm.mutex.Unlock()
+	if total != 30 || tracked != 2 {
+		t.Fatalf("删除后总字节数 %d，堆中 %d 个进程", total, tracked)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	logPumpBuffer = 1024 // 每个进程等待写入日志缓冲的最大行数，写满时输出协程等待
+	logPumpBatch  = 256  // 每次获取 logMutex 最多写入的行数
+)
+
+// logEntry 等待写入日志缓冲的一行输出
+type logEntry struct {
+	time   time.Time
+	prefix string // STDOUT 或 STDERR
+	line   string
+	keep   bool // 未被 log_filter 过滤，需要保留在日志缓冲中
+}
+
+// logPump 每个进程一个，标准输出和标准错误的 logWriter 只把行投递到通道，
+// 由专门的协程批量写入日志缓冲，输出再多也只在写入批次时短暂持有 logMutex
+type logPump struct {
+	pm      *ProcessManager
+	name    string
+	entries chan logEntry
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+
+	startedAt time.Time // 进程启动时间，在 cmd.Start 之前设置，接管的进程为零值
+	ready     bool      // 已收到第一行输出，只在写入协程中访问
+}
+
+// newLogPump 创建进程的日志写入协程
+func (pm *ProcessManager) newLogPump(name string) *logPump {
+	p := &logPump{
+		pm:      pm,
+		name:    name,
+		entries: make(chan logEntry, logPumpBuffer),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// send 投递一行输出，通道写满时等待；Close 之后投递的行直接丢弃
+func (p *logPump) send(entry logEntry) {
+	select {
+	case p.entries <- entry:
+	case <-p.done:
+	}
+}
+
+func (p *logPump) run() {
+	defer close(p.done)
+
+	batch := make([]logEntry, 0, logPumpBatch)
+	for {
+		select {
+		case entry := <-p.entries:
+			batch = p.drain(append(batch[:0], entry))
+			p.write(batch)
+		case <-p.stop:
+			// 写完 Close 之前投递的所有行
+			for {
+				batch = p.drain(batch[:0])
+				if len(batch) == 0 {
+					return
+				}
+				p.write(batch)
+			}
+		}
+	}
+}
+
+// drain 不等待地取出通道中已有的行，直到批次写满
+func (p *logPump) drain(batch []logEntry) []logEntry {
+	for len(batch) < logPumpBatch {
+		select {
+		case entry := <-p.entries:
+			batch = append(batch, entry)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// write 记录一批输出到主日志，并在一次加锁内追加到日志缓冲
+func (p *logPump) write(batch []logEntry) {
+	// 主日志不受过滤影响
+	for _, entry := range batch {
+		logOutputf("进程 %s %s: %s", p.name, entry.prefix, entry.line)
+	}
+
+	p.pm.logMutex.Lock()
+	defer p.pm.logMutex.Unlock()
+
+	status, exists := p.pm.outputs[p.name]
+	if !exists {
+		return
+	}
+
+	// 第一行输出（包括被 log_filter 过滤的行）视为进程就绪，记录启动耗时
+	if !p.ready && !p.startedAt.IsZero() {
+		p.ready = true
+		status.StartupDuration = batch[0].time.Sub(p.startedAt).Seconds()
+	}
+	status.LastOutputTime = batch[len(batch)-1].time
+
+	for _, entry := range batch {
+		if !entry.keep {
+			continue
+		}
+		logLine := fmt.Sprintf("[%s] %s: %s", entry.time.Format(p.pm.logLayout), entry.prefix, entry.line)
+		p.pm.appendOutput(status, logLine)
+	}
+}
+
+// Close 等待已投递的行全部写入后停止协程，在进程输出全部读取完成后调用，
+// 之后再记录的日志（如进程退出信息）会排在进程输出之后
+func (p *logPump) Close() {
+	p.once.Do(func() { close(p.stop) })
+	<-p.done
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// logSearchMaxResults 日志搜索最多返回的匹配行数
+const logSearchMaxResults = 1000
+
+// logMatch 日志搜索匹配到的一行
+type logMatch struct {
+	Source    string `json:"source"`              // buffer 为日志缓冲，stdout/stderr 为分离模式下的输出文件
+	Timestamp string `json:"timestamp,omitempty"` // 日志缓冲中记录的时间，输出文件中的行没有时间戳
+	Line      string `json:"line"`
+}
+
+// SearchLogs 在所有进程的日志缓冲中搜索匹配的行，启用 detach_on_exit 时也搜索磁盘上的输出文件，
+// 文件中的行与日志缓冲一样抹除敏感值，已在日志缓冲中的行不重复返回。
+// 结果按进程名称分组，总数超过 limit 时截断并返回 true
+func (pm *ProcessManager) SearchLogs(match func(string) bool, limit int) (map[string][]logMatch, bool) {
+	type target struct {
+		name    string
+		lines   []string
+		stdout  string
+		stderr  string
+		secrets []string
+	}
+
+	pm.mutex.RLock()
+	targets := make([]target, 0, len(pm.processes))
+	for name, status := range pm.processes {
+		t := target{name: name, lines: pm.outputLines(status)}
+		if pm.config != nil && pm.config.Server.DetachOnExit {
+			t.stdout, t.stderr = pm.outputPaths(name)
+			t.secrets = secretValues(status.Config.Environment, effectiveSecretKeys(pm.config.Server))
+		}
+		targets = append(targets, t)
+	}
+	pm.mutex.RUnlock()
+
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].name < targets[j].name
+	})
+
+	results := make(map[string][]logMatch)
+	total := 0
+	add := func(name string, m logMatch) bool {
+		if total >= limit {
+			return false
+		}
+		results[name] = append(results[name], m)
+		total++
+		return true
+	}
+
+	for _, t := range targets {
+		for _, line := range t.lines {
+			if !match(line) {
+				continue
+			}
+			timestamp, text := splitLogTimestamp(line)
+			if !add(t.name, logMatch{Source: "buffer", Timestamp: timestamp, Line: text}) {
+				return results, true
+			}
+		}
+
+		buffered := bufferedLines(t.lines)
+		for _, file := range []struct{ source, path string }{{"stdout", t.stdout}, {"stderr", t.stderr}} {
+			if file.path == "" {
+				continue
+			}
+			if !searchLogFile(file.path, t.secrets, buffered[file.source], match, func(line string) bool {
+				return add(t.name, logMatch{Source: file.source, Line: line})
+			}) {
+				return results, true
+			}
+		}
+	}
+	return results, false
+}
+
+// bufferedLines 按输出来源（stdout、stderr）统计日志缓冲中每种行出现的次数
+func bufferedLines(lines []string) map[string]map[string]int {
+	counts := map[string]map[string]int{"stdout": {}, "stderr": {}}
+	for _, line := range lines {
+		_, text := splitLogTimestamp(line)
+		if rest, ok := strings.CutPrefix(text, "STDOUT: "); ok {
+			counts["stdout"][rest]++
+		} else if rest, ok := strings.CutPrefix(text, "STDERR: "); ok {
+			counts["stderr"][rest]++
+		}
+	}
+	return counts
+}
+
+// searchLogFile 逐行搜索输出文件，文件不存在时跳过；found 返回 false 时停止搜索并返回 false。
+// 每行先抹除敏感值再匹配。日志缓冲中的行是文件中最后写入的那部分，
+// 因此 buffered 中的每种行跳过其在文件中最后出现的相应次数，避免与缓冲中的结果重复
+func searchLogFile(path string, secrets []string, buffered map[string]int, match func(string) bool, found func(string) bool) bool {
+	// 先统计缓冲中的行在文件中出现的总次数，得出每种行从文件开头起需要保留的次数
+	keep := make(map[string]int)
+	if len(buffered) > 0 {
+		scanLogFile(path, secrets, func(line string) bool {
+			if _, ok := buffered[line]; ok {
+				keep[line]++
+			}
+			return true
+		})
+		for line, n := range keep {
+			keep[line] = max(n-buffered[line], 0)
+		}
+	}
+
+	return scanLogFile(path, secrets, func(line string) bool {
+		if n, ok := keep[line]; ok {
+			if n == 0 {
+				return true
+			}
+			keep[line] = n - 1
+		}
+		return !match(line) || found(line)
+	})
+}
+
+// scanLogFile 逐行读取输出文件，去掉首尾空白、跳过空行并抹除敏感值后交给 each，
+// 文件不存在时跳过；each 返回 false 时停止读取并返回 false
+func scanLogFile(path string, secrets []string, each func(string) bool) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logWarnf("搜索日志文件 %s 失败: %v", path, err)
+		}
+		return true
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !each(scrubSecrets(line, secrets)) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitLogTimestamp 拆分日志缓冲中的行首时间戳 "[时间] 内容"
+func splitLogTimestamp(line string) (string, string) {
+	if strings.HasPrefix(line, "[") {
+		if i := strings.Index(line, "] "); i > 0 {
+			return line[1:i], line[i+2:]
+		}
+	}
+	return "", line
+}
+
+// 日志搜索 API
+func (pm *ProcessManager) handleLogSearch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	fail := func(message string) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	q := query.Get("q")
+	if q == "" {
+		fail("缺少搜索内容参数 q")
+		return
+	}
+
+	match := func(line string) bool {
+		return strings.Contains(line, q)
+	}
+	if query.Get("regex") == "true" {
+		pattern, err := regexp.Compile(q)
+		if err != nil {
+			fail("无效的正则表达式: " + err.Error())
+			return
+		}
+		match = pattern.MatchString
+	}
+
+	limit := logSearchMaxResults
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			fail("limit 必须是正整数")
+			return
+		}
+		limit = min(n, logSearchMaxResults)
+	}
+
+	results, truncated := pm.SearchLogs(match, limit)
+	total := 0
+	for _, matches := range results {
+		total += len(matches)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"results":   results,
+		"total":     total,
+		"truncated": truncated,
+	})
+}
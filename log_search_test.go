@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestSearchLogsScrubsAndDedupesFiles(t *testing.T) {
+	pm, _ := newTestManager(t, `
+server:
+  detach_on_exit: true
+processes:
+  - name: svc
+    command: fake-sleep
+    environment:
+      API_TOKEN: hunter2
+`)
+
+	// 输出文件中最后两行也在日志缓冲中，较早出现的同样内容不是重复
+	stdout, _ := pm.outputPaths("svc")
+	if err := os.MkdirAll(pm.stateDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stdout, []byte("tick\ntoken=hunter2\ntick\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pm.mutex.Lock()
+	pm.logMutex.Lock()
+	pm.appendOutput(pm.processes["svc"], "[10:00:00] STDOUT: token=***")
+	pm.appendOutput(pm.processes["svc"], "[10:00:01] STDOUT: tick")
+	pm.logMutex.Unlock()
+	pm.mutex.Unlock()
+
+	results, truncated := pm.SearchLogs(func(string) bool { return true }, logSearchMaxResults)
+	if truncated {
+		t.Fatal("结果被截断")
+	}
+	want := []logMatch{
+		{Source: "buffer", Timestamp: "10:00:00", Line: "STDOUT: token=***"},
+		{Source: "buffer", Timestamp: "10:00:01", Line: "STDOUT: tick"},
+		{Source: "stdout", Line: "tick"},
+	}
+	if got := results["svc"]; !slices.Equal(got, want) {
+		t.Fatalf("搜索结果为 %+v，期望 %+v", got, want)
+	}
+
+	// 搜索敏感值本身也不能命中文件中的原始内容
+	results, _ = pm.SearchLogs(func(line string) bool { return line == "token=hunter2" }, logSearchMaxResults)
+	if len(results) != 0 {
+		t.Fatalf("按敏感值搜索命中了 %+v", results)
+	}
+}
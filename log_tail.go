@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	logSubscriberBuffer = 256              // 每个订阅者等待发送的最大行数，客户端读取过慢时丢弃新行
+	tailHeartbeat       = 15 * time.Second // 没有新日志时发送注释行的间隔，保持代理连接并及时发现客户端断开
+)
+
+// logSubscriber 实时日志的订阅者，新追加到日志缓冲的行投递到 lines
+type logSubscriber struct {
+	lines   chan string  // 进程从配置中移除时关闭
+	dropped atomic.Int64 // 通道写满被丢弃、尚未通知客户端的行数
+}
+
+// logSubscribers 一个进程的所有实时日志订阅者
+type logSubscribers map[*logSubscriber]struct{}
+
+// subscribeLogs 订阅进程的新日志，同时返回订阅时日志缓冲中的内容，两者之间不会遗漏或重复。
+// 进程不存在时返回 false
+func (pm *ProcessManager) subscribeLogs(name string) (*logSubscriber, []string, bool) {
+	pm.logMutex.Lock()
+	defer pm.logMutex.Unlock()
+
+	status, exists := pm.outputs[name]
+	if !exists {
+		return nil, nil, false
+	}
+	sub := &logSubscriber{lines: make(chan string, logSubscriberBuffer)}
+	if pm.subscribers[name] == nil {
+		pm.subscribers[name] = make(logSubscribers)
+	}
+	pm.subscribers[name][sub] = struct{}{}
+	return sub, append([]string(nil), status.Output...), true
+}
+
+// unsubscribeLogs 取消订阅，客户端断开时调用
+func (pm *ProcessManager) unsubscribeLogs(name string, sub *logSubscriber) {
+	pm.logMutex.Lock()
+	defer pm.logMutex.Unlock()
+
+	subs := pm.subscribers[name]
+	if _, exists := subs[sub]; !exists {
+		return // 进程已移除，通道已关闭
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(pm.subscribers, name)
+	}
+}
+
+// publishLog 把新日志投递给进程的所有订阅者，不等待读取过慢的订阅者。调用方需持有 logMutex
+func (pm *ProcessManager) publishLog(name, line string) {
+	for sub := range pm.subscribers[name] {
+		select {
+		case sub.lines <- line:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// closeSubscribers 进程从配置中移除时结束其所有订阅。调用方需持有 logMutex
+func (pm *ProcessManager) closeSubscribers(name string) {
+	for sub := range pm.subscribers[name] {
+		close(sub.lines)
+	}
+	delete(pm.subscribers, name)
+}
+
+// writeEvent 写入一个 Server-Sent Events 事件，event 为空时是默认的 message 事件
+func writeEvent(w http.ResponseWriter, event, data string) error {
+	var b strings.Builder
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event)
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	_, err := w.Write([]byte(b.String()))
+	return err
+}
+
+// 实时日志 API（Server-Sent Events），先发送日志缓冲中已有的行，之后每追加一行发送一个事件。
+// ?lines=N 只发送缓冲中最近的 N 行；客户端读取过慢丢弃行时发送 dropped 事件，进程从配置中移除时发送 end 事件
+func (pm *ProcessManager) handleTail(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	fail := func(status int, message string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   message,
+		})
+	}
+
+	limit, err := parseNonNegativeInt(r.URL.Query().Get("lines"), 0)
+	if err != nil {
+		fail(http.StatusBadRequest, fmt.Sprintf("无效的 lines 参数: %v", err))
+		return
+	}
+
+	sub, initial, exists := pm.subscribeLogs(name)
+	if !exists {
+		fail(http.StatusNotFound, "进程不存在")
+		return
+	}
+	defer pm.unsubscribeLogs(name, sub)
+
+	// 长连接不受 write_timeout 限制
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // 关闭 nginx 等反向代理的缓冲
+	w.WriteHeader(http.StatusOK)
+
+	if limit > 0 && len(initial) > limit {
+		initial = initial[len(initial)-limit:]
+	}
+	for _, line := range initial {
+		writeEvent(w, "", line)
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(tailHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case line, ok := <-sub.lines:
+			if !ok {
+				writeEvent(w, "end", "进程已从配置中移除")
+				rc.Flush()
+				return
+			}
+			if n := sub.dropped.Swap(0); n > 0 {
+				writeEvent(w, "dropped", fmt.Sprint(n))
+			}
+			if err := writeEvent(w, "", line); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// LogLevel 日志级别
+type LogLevel int32
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+// currentLogLevel 当前日志级别，默认为 info
+var currentLogLevel atomic.Int32
+
+func init() {
+	currentLogLevel.Store(int32(LogLevelInfo))
+
+	// keeper 自身的日志同时写入标准错误和内存缓冲
+	log.SetOutput(io.MultiWriter(os.Stderr, keeperLogs))
+}
+
+// maxKeeperLogLines keeper 自身日志缓冲保留的行数
+const maxKeeperLogLines = 500
+
+// keeperLogBuffer 保存 keeper 自身最近的日志（配置加载、重启决策、退出等），供 /api/keeper/logs 查看
+type keeperLogBuffer struct {
+	mutex sync.Mutex
+	lines []string
+}
+
+// keeperLogs keeper 自身日志缓冲，进程输出不写入这里
+var keeperLogs = &keeperLogBuffer{}
+
+// Write 实现 io.Writer，log 每次写入一条完整日志，多行内容按行拆分，超出上限时丢弃最早的行
+func (b *keeperLogBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		b.lines = append(b.lines, line)
+	}
+	if len(b.lines) > maxKeeperLogLines {
+		b.lines = b.lines[len(b.lines)-maxKeeperLogLines:]
+	}
+	return len(p), nil
+}
+
+// Lines 返回最近的 n 行日志，n 不大于 0 时返回全部
+func (b *keeperLogBuffer) Lines(n int) []string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	lines := b.lines
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return append([]string(nil), lines...)
+}
+
+// outputLogger 记录进程输出的日志，只写入标准错误，避免进程输出挤掉 keeper 日志缓冲中的内容
+var outputLogger = log.New(os.Stderr, "", log.LstdFlags)
+
+// parseLogLevel 解析日志级别字符串
+func parseLogLevel(level string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return LogLevelDebug, nil
+	case "", "info":
+		return LogLevelInfo, nil
+	case "warn", "warning":
+		return LogLevelWarn, nil
+	case "error":
+		return LogLevelError, nil
+	default:
+		return LogLevelInfo, fmt.Errorf("未知的日志级别: %s，支持 debug, info, warn, error", level)
+	}
+}
+
+// setLogLevel 设置日志级别
+func setLogLevel(level LogLevel) {
+	currentLogLevel.Store(int32(level))
+}
+
+// logEnabled 判断指定级别的日志是否需要输出
+func logEnabled(level LogLevel) bool {
+	return level >= LogLevel(currentLogLevel.Load())
+}
+
+// logTimeFormats 进程日志时间戳格式预设
+var logTimeFormats = map[string]string{
+	"":            "15:04:05",
+	"time":        "15:04:05",
+	"time_ms":     "15:04:05.000",
+	"datetime":    "2006-01-02 15:04:05",
+	"datetime_ms": "2006-01-02 15:04:05.000",
+	"iso8601":     "2006-01-02T15:04:05.000Z07:00",
+}
+
+// resolveLogTimeFormat 将时间戳格式名称解析为 Go 时间格式，非预设名称按 Go 时间格式原样使用
+func resolveLogTimeFormat(format string) string {
+	if layout, ok := logTimeFormats[strings.ToLower(strings.TrimSpace(format))]; ok {
+		return layout
+	}
+	return format
+}
+
+// logDebugf 输出调试日志
+func logDebugf(format string, v ...interface{}) {
+	if logEnabled(LogLevelDebug) {
+		log.Printf(format, v...)
+	}
+}
+
+// logInfof 输出信息日志
+func logInfof(format string, v ...interface{}) {
+	if logEnabled(LogLevelInfo) {
+		log.Printf(format, v...)
+	}
+}
+
+// logWarnf 输出警告日志
+func logWarnf(format string, v ...interface{}) {
+	if logEnabled(LogLevelWarn) {
+		log.Printf(format, v...)
+	}
+}
+
+// logErrorf 输出错误日志
+func logErrorf(format string, v ...interface{}) {
+	if logEnabled(LogLevelError) {
+		log.Printf(format, v...)
+	}
+}
+
+// logOutputf 以 info 级别记录进程输出，不写入 keeper 日志缓冲
+func logOutputf(format string, v ...interface{}) {
+	if logEnabled(LogLevelInfo) {
+		outputLogger.Printf(format, v...)
+	}
+}
@@ -1,61 +1,245 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"compress/gzip"
 	"context"
+	"embed"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"html/template"
+	"io"
 	"log"
+	"maps"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/goccy/go-yaml"
 )
 
 // ProcessConfig 进程配置
 type ProcessConfig struct {
-	Name         string            `json:"name" yaml:"name"`
-	Command      string            `json:"command" yaml:"command"`
-	Args         []string          `json:"args" yaml:"args"`
-	WorkDir      string            `json:"workdir" yaml:"workdir"`
-	AutoRestart  bool              `json:"auto_restart" yaml:"auto_restart"`
-	Enabled      bool              `json:"enabled" yaml:"enabled"`
-	Environment  map[string]string `json:"environment" yaml:"environment"`
-	User         string            `json:"user" yaml:"user"`
-	MaxRestarts  int               `json:"max_restarts" yaml:"max_restarts"`
-	RestartDelay int               `json:"restart_delay" yaml:"restart_delay"` // 重启延迟秒数
-	Description  string            `json:"description" yaml:"description"`
+	Name                   string            `json:"name" yaml:"name"`
+	Command                string            `json:"command" yaml:"command"`
+	Args                   []string          `json:"args" yaml:"args"`
+	WorkDir                string            `json:"workdir" yaml:"workdir"`
+	AutoRestart            bool              `json:"auto_restart" yaml:"auto_restart"`
+	Enabled                bool              `json:"enabled" yaml:"enabled"`
+	Environment            map[string]string `json:"environment" yaml:"environment"`
+	User                   string            `json:"user" yaml:"user"`
+	MaxRestarts            int               `json:"max_restarts" yaml:"max_restarts"`
+	RestartDelay           int               `json:"restart_delay" yaml:"restart_delay"` // 重启延迟秒数
+	Description            string            `json:"description" yaml:"description"`
+	TrimEmptyArgs          bool              `json:"trim_empty_args" yaml:"trim_empty_args"`                     // 是否丢弃空字符串参数，默认保留
+	Type                   string            `json:"type" yaml:"type"`                                           // 进程类型：simple（默认）, forking, oneshot
+	PIDFile                string            `json:"pidfile" yaml:"pidfile"`                                     // forking 类型进程写入的 PID 文件
+	StartupGrace           int               `json:"startup_grace" yaml:"startup_grace"`                         // 启动保护期秒数，期间退出视为启动失败，0 表示不检查
+	RestartWindow          int               `json:"restart_window" yaml:"restart_window"`                       // 重启计数的滑动窗口秒数，窗口内重启达到 max_restarts 才禁用，0 表示累计计数
+	RestartCountResetAfter int               `json:"restart_count_reset_after" yaml:"restart_count_reset_after"` // 连续运行多少秒后自动重置重启计数，0 表示不自动重置
+	Interval               int               `json:"interval" yaml:"interval"`                                   // 周期运行间隔秒数，每次运行结束后等待该时间再次运行，0 表示常驻进程
+	LogFilter              string            `json:"log_filter" yaml:"log_filter"`                               // 输出过滤正则，只有匹配的行保留在日志缓冲中
+	LogHighlight           string            `json:"log_highlight" yaml:"log_highlight"`                         // 高亮正则，匹配的日志行在界面中标红
+	CreateWorkDir          bool              `json:"create_workdir" yaml:"create_workdir"`                       // 工作目录不存在时在启动前自动创建
+	WorkDirMode            string            `json:"workdir_mode" yaml:"workdir_mode"`                           // 自动创建工作目录的权限（八进制），默认 0755
+	Shell                  bool              `json:"shell" yaml:"shell"`                                         // 通过 shell 执行 command，支持管道和重定向
+	ShellPath              string            `json:"shell_path" yaml:"shell_path"`                               // shell 模式使用的 shell，默认 /bin/sh
+	Umask                  string            `json:"umask" yaml:"umask"`                                         // 子进程的 umask（八进制，如 "022"），为空时继承 keeper 的 umask
+	Tags                   []string          `json:"tags" yaml:"tags"`                                           // 进程标签，用于按分组批量操作和界面过滤
+	ProcessGroup           string            `json:"process_group" yaml:"process_group"`                         // 进程组模式：own（默认，独立进程组）, session（新会话）, inherit（留在 keeper 的进程组）
+	StdinData              string            `json:"stdin_data" yaml:"stdin_data"`                               // 启动后写入子进程标准输入的内容
+	StdinFile              string            `json:"stdin_file" yaml:"stdin_file"`                               // 启动后写入子进程标准输入的文件，相对路径相对于配置文件所在目录
+	StdoutFile             string            `json:"stdout_file" yaml:"stdout_file"`                             // 标准输出额外写入的文件，相对路径相对于配置文件所在目录，keeper 仍同时捕获输出
+	StderrFile             string            `json:"stderr_file" yaml:"stderr_file"`                             // 标准错误额外写入的文件，可以与 stdout_file 相同
+	OutputFileMode         string            `json:"output_file_mode" yaml:"output_file_mode"`                   // stdout_file、stderr_file 的打开方式：append（默认）或 truncate（每次启动时清空）
+	OutputFileMaxSize      int64             `json:"output_file_max_size" yaml:"output_file_max_size"`           // stdout_file、stderr_file 超过该字节数后轮转为 file.1、file.2...，0 表示不轮转
+	OutputFileMaxBackups   int               `json:"output_file_max_backups" yaml:"output_file_max_backups"`     // 保留的轮转文件数，默认 5
+	OutputFileCompress     bool              `json:"output_file_compress" yaml:"output_file_compress"`           // 轮转后的文件以 gzip 压缩保存为 file.1.gz
+	StdinOpen              bool              `json:"stdin_open" yaml:"stdin_open"`                               // 写入初始内容后保持标准输入打开，可通过 API 继续写入
+	PreStart               string            `json:"pre_start" yaml:"pre_start"`                                 // 启动前通过 shell 执行的检查命令，退出码非 0 时不启动进程
+	PostStop               string            `json:"post_stop" yaml:"post_stop"`                                 // 进程退出（包括主动停止）后通过 shell 执行的清理命令
+	HookTimeout            int               `json:"hook_timeout" yaml:"hook_timeout"`                           // pre_start、post_stop 钩子命令的超时秒数，默认 30
+	Watch                  []string          `json:"watch" yaml:"watch"`                                         // 监视的文件或目录，发生变化后自动重启进程，相对路径相对于配置文件所在目录
+	WatchDebounce          float64           `json:"watch_debounce" yaml:"watch_debounce"`                       // 最后一次文件变化后等待多少秒没有新变化才重启，默认 1
+	MaxRuntime             int               `json:"max_runtime" yaml:"max_runtime"`                             // 单次运行的最长秒数，超时后停止进程，oneshot 任务记为失败，0 表示不限制
+	MinRestartInterval     int               `json:"min_restart_interval" yaml:"min_restart_interval"`           // 两次启动之间的最小间隔秒数，包括手动启动和重启，间隔内的启动排队等待，0 表示不限制
+	AutoStart              *bool             `json:"auto_start" yaml:"auto_start"`                               // keeper 启动时是否自动启动该进程，未设置时为 true；为 false 时进程仍受管理，可手动或按调度启动
+	SilenceTimeout         int               `json:"silence_timeout" yaml:"silence_timeout"`                     // 运行中超过该秒数没有输出时标记为疑似卡住（silent），0 表示不检查
+	StopSequence           []StopStep        `json:"stop_sequence" yaml:"stop_sequence"`                         // 停止进程时依次发送的信号和等待秒数，全部步骤后仍未退出则 SIGKILL，默认 SIGTERM 后等待 5 秒
+	OnOrphan               string            `json:"on_orphan" yaml:"on_orphan"`                                 // keeper 异常退出后遗留的进程在下次启动时的处理方式：ignore（默认）, kill, adopt
+	LockFile               string            `json:"lock_file" yaml:"lock_file"`                                 // 启动前加排他锁（flock）的文件，锁被占用时拒绝启动，进程退出后释放，相对路径相对于配置文件所在目录
+	OnMaxRestarts          string            `json:"on_max_restarts" yaml:"on_max_restarts"`                     // 达到 max_restarts 后的处理方式：disable（默认，禁用自动重启）, slow-retry, alert-only
+	SlowRetryInterval      int               `json:"slow_retry_interval" yaml:"slow_retry_interval"`             // on_max_restarts 为 slow-retry 时的重试间隔秒数，默认 300
+	AlertCommand           string            `json:"alert_command" yaml:"alert_command"`                         // 达到 max_restarts 或资源占用持续超过 alert 阈值时通过 shell 执行的告警命令，告警信息通过 KEEPER_ 开头的环境变量传入
+	Alert                  ResourceAlert     `json:"alert" yaml:"alert"`                                         // CPU、内存占用的告警阈值，持续超过 alert.duration 秒后告警
+	Order                  int               `json:"order" yaml:"order"`                                         // 在 Web 界面和进程列表中的排序，数值小的在前，相同时按名称排序，默认 0
+}
+
+// autoStarts 判断 keeper 启动时是否自动启动该进程，未设置 auto_start 时为 true
+func (c ProcessConfig) autoStarts() bool {
+	return c.AutoStart == nil || *c.AutoStart
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Port        string `json:"port" yaml:"port"`
-	Host        string `json:"host" yaml:"host"`
-	RefreshTime int    `json:"refresh_time" yaml:"refresh_time"` // 页面刷新时间
+	Port                  string   `json:"port" yaml:"port"`
+	Host                  string   `json:"host" yaml:"host"`
+	RefreshTime           int      `json:"refresh_time" yaml:"refresh_time"`                       // 页面刷新时间
+	StrictValidation      bool     `json:"strict_validation" yaml:"strict_validation"`             // 严格校验：加载配置时检查命令是否存在
+	LogLevel              string   `json:"log_level" yaml:"log_level"`                             // 日志级别：debug, info, warn, error
+	MaxConcurrentRestarts int      `json:"max_concurrent_restarts" yaml:"max_concurrent_restarts"` // 同时进行的自动重启数量上限，0 表示不限制
+	RestartIntervalMs     int      `json:"restart_interval_ms" yaml:"restart_interval_ms"`         // 任意两次自动重启之间的最小间隔毫秒数，0 表示不限制
+	LogTimeFormat         string   `json:"log_time_format" yaml:"log_time_format"`                 // 进程日志时间戳格式：time, time_ms, datetime, datetime_ms, iso8601 或 Go 时间格式
+	MaxTotalLogBytes      int      `json:"max_total_log_bytes" yaml:"max_total_log_bytes"`         // 所有进程日志缓冲的总字节数上限，0 表示不限制
+	SecretKeys            []string `json:"secret_keys" yaml:"secret_keys"`                         // 敏感环境变量名称关键字，匹配的值在 API 和日志中脱敏，为空时使用默认关键字
+	UnixSocket            string   `json:"unix_socket" yaml:"unix_socket"`                         // 监听的 Unix 套接字路径，设置后不再监听 TCP 端口
+	DetachOnExit          bool     `json:"detach_on_exit" yaml:"detach_on_exit"`                   // keeper 退出时不停止子进程，下次启动时重新接管
+	StateDir              string   `json:"state_dir" yaml:"state_dir"`                             // 分离模式保存状态和进程输出的目录，默认为配置文件所在目录下的 .keeper
+	DetachOutputMaxSize   int64    `json:"detach_output_max_size" yaml:"detach_output_max_size"`   // 分离模式输出文件的字节数上限，keeper 读完后超过上限时清空，默认 64 MiB
+	RateLimit             float64  `json:"rate_limit" yaml:"rate_limit"`                           // 每个来源地址只读请求每秒上限，0 表示不限制
+	ControlRateLimit      float64  `json:"control_rate_limit" yaml:"control_rate_limit"`           // 每个来源地址修改状态的请求每秒上限，0 表示不限制
+	RateLimitBurst        int      `json:"rate_limit_burst" yaml:"rate_limit_burst"`               // 允许的突发请求数，默认等于每秒上限
+	StartupStagger        float64  `json:"startup_stagger" yaml:"startup_stagger"`                 // keeper 启动时依次启动进程的间隔秒数，0 表示不等待
+	MaxConcurrent         int      `json:"max_concurrent" yaml:"max_concurrent"`                   // 同时运行的进程数量上限，达到上限后启动的进程排队等待，0 表示不限制
+	Listen                []string `json:"listen" yaml:"listen"`                                   // 监听地址列表（如 127.0.0.1:8080、[::1]:8080），设置后忽略 host，没有端口的地址使用 port
+	ReadOnly              bool     `json:"read_only" yaml:"read_only"`                             // 只读模式：只允许查看状态和日志，所有修改状态的请求返回 403
+	RestartJitter         float64  `json:"restart_jitter" yaml:"restart_jitter"`                   // 自动重启延迟的随机浮动比例（0.2 表示 ±20%），避免同时崩溃的进程同时重启，0 表示不浮动
+	MaxLineLength         int      `json:"max_line_length" yaml:"max_line_length"`                 // 日志缓冲中单行输出的最大字节数，超出部分截断，stdout_file 等文件仍写入完整内容，默认 65536
+	ReadTimeout           int      `json:"read_timeout" yaml:"read_timeout"`                       // 读取整个请求（包括请求体）的超时秒数，默认 30
+	WriteTimeout          int      `json:"write_timeout" yaml:"write_timeout"`                     // 写出响应的超时秒数，从读完请求头开始计算，默认 120
+	IdleTimeout           int      `json:"idle_timeout" yaml:"idle_timeout"`                       // keep-alive 连接空闲多少秒后关闭，默认 120
+}
+
+// ServerOverrides 命令行参数对服务器配置的覆盖
+type ServerOverrides struct {
+	Host     string
+	Port     string
+	LogLevel string
 }
 
 // Config 总配置
 type Config struct {
-	Server    ServerConfig    `json:"server" yaml:"server"`
-	Processes []ProcessConfig `json:"processes" yaml:"processes"`
+	Server    ServerConfig             `json:"server" yaml:"server"`
+	Include   []string                 `json:"include,omitempty" yaml:"include,omitempty"` // 额外加载进程配置的文件（支持通配符，相对于主配置文件目录）
+	Processes []ProcessConfig          `json:"processes" yaml:"processes"`
+	Profiles  map[string]ProfileConfig `json:"profiles,omitempty" yaml:"profiles,omitempty"` // 按环境覆盖进程配置，通过 --profile 或 KEEPER_PROFILE 选择
+}
+
+// 进程停止原因
+const (
+	StopReasonManual    = "manual"    // 通过 keeper 主动停止
+	StopReasonExited    = "exited"    // 进程自行正常退出
+	StopReasonCrashed   = "crashed"   // 进程异常退出或被信号终止
+	StopReasonDisabled  = "disabled"  // 重启次数过多被禁用
+	StopReasonScheduled = "scheduled" // 周期进程本次运行结束，等待下一次运行
+	StopReasonTimeout   = "timeout"   // 运行超过 max_runtime 被停止
+)
+
+// stopReasonLabels 停止原因在界面上显示的名称
+var stopReasonLabels = map[string]string{
+	StopReasonManual:    "手动停止",
+	StopReasonExited:    "正常退出",
+	StopReasonCrashed:   "异常退出",
+	StopReasonDisabled:  "已禁用",
+	StopReasonScheduled: "等待调度",
+	StopReasonTimeout:   "运行超时",
+}
+
+// StopReasonLabel 返回停止原因的显示名称，供页面模板使用
+func (s *ProcessStatus) StopReasonLabel() string {
+	return stopReasonLabels[s.StopReason]
 }
 
 // ProcessStatus 进程状态
 type ProcessStatus struct {
-	Config       ProcessConfig `json:"config"`
-	PID          int           `json:"pid"`
-	Status       string        `json:"status"` // running, stopped, error, disabled
-	StartTime    time.Time     `json:"start_time"`
-	Restarts     int           `json:"restarts"`
-	LastError    string        `json:"last_error"`
-	LastExitCode int           `json:"last_exit_code"`
-	Output       []string      `json:"output"` // 最近的输出日志
+	Config          ProcessConfig    `json:"config"`
+	PID             int              `json:"pid"`
+	Status          string           `json:"status"` // starting, running, paused, stopped, error, disabled, completed, failed, waiting, pending
+	StartTime       time.Time        `json:"start_time"`
+	Restarts        int              `json:"restarts"`
+	RestartHistory  []time.Time      `json:"restart_history"` // 最近的异常退出重启时间，最多保留 maxRestartHistory 条
+	LastError       string           `json:"last_error"`
+	LastExitCode    int              `json:"last_exit_code"`
+	ExitCodeHistory []int            `json:"exit_code_history"` // 最近的退出码（不含手动停止），最多保留 maxExitCodeHistory 条
+	Output          []string         `json:"output"`            // 最近的输出日志
+	CommandLine     []string         `json:"command_line"`      // 最近一次启动实际执行的命令行（含 sudo 前缀）
+	LastDuration    float64          `json:"last_duration"`     // 周期进程最近一次运行耗时（秒）
+	NextRunTime     time.Time        `json:"next_run_time"`     // 周期进程下一次运行时间
+	LogWriteError   string           `json:"log_write_error"`   // 最近一次输出写入额外目标（如日志文件）失败的原因，恢复后清空
+	StopReason      string           `json:"stop_reason"`       // 最近一次停止的原因：manual, exited, crashed, disabled, scheduled, timeout，启动后清空
+	StartupDuration float64          `json:"startup_duration"`  // 最近一次启动到输出第一行的秒数，尚未输出时为 0
+	LastOutputTime  time.Time        `json:"last_output_time"`  // 最近一次输出的时间（包括被 log_filter 过滤的行），进程停止后保留
+	Silent          bool             `json:"silent"`            // 运行中但超过 silence_timeout 秒没有输出，可能已卡住，获取状态时计算
+	Preflight       *PreflightResult `json:"preflight"`         // 最近一次启动前检查的结果，加载配置时更新
+	BytesStdin      int64            `json:"bytes_stdin"`       // 累计写入标准输入的字节数，获取状态时计算
+	BytesStdout     int64            `json:"bytes_stdout"`      // 累计标准输出的字节数（包括被过滤和截断的内容），获取状态时计算
+	BytesStderr     int64            `json:"bytes_stderr"`      // 累计标准错误的字节数，获取状态时计算
+	CPUPercent      float64          `json:"cpu_percent"`       // 最近一次采样的 CPU 占用百分比，仅配置了 alert 时采样
+	MemoryBytes     int64            `json:"memory_bytes"`      // 最近一次采样的常驻内存字节数，仅配置了 alert 时采样
+	ResourceAlert   string           `json:"resource_alert"`    // 资源占用持续超过 alert 阈值的说明，恢复正常或进程退出后清空
+
+	restartsResetAt time.Time   // 最近一次重置重启计数的时间，之前的重启历史不计入窗口
+	outputBytes     int         // Output 占用的字节数
+	heapIndex       int         // 在 outputHeap 中的位置，不在堆中时为 -1
+	environment     []string    // 最近一次启动时实际使用的环境变量（含继承的 os.Environ）
+	counters        *ioCounters // 标准输入、输出的字节计数，进程加入管理后历次运行累计
+	restartAlerted  bool        // 本次达到重启次数上限后是否已告警，重启计数回落到上限以下时清除
+}
+
+// ioCounters 进程标准输入、输出的累计字节数，由输出复制协程和标准输入写入方直接原子递增，不需要加锁
+type ioCounters struct {
+	stdin  atomic.Int64
+	stdout atomic.Int64
+	stderr atomic.Int64
+}
+
+// maxRestartHistory 每个进程保留的重启历史条数
+const maxRestartHistory = 20
+
+// maxExitCodeHistory 每个进程保留的退出码历史条数
+const maxExitCodeHistory = 10
+
+// 进程操作错误类别，用于区分错误原因（例如映射为 HTTP 状态码）
+var (
+	ErrProcessNotFound   = errors.New("进程不存在")
+	ErrProcessRunning    = errors.New("进程已经在运行")
+	ErrProcessNotRunning = errors.New("进程没有运行")
+	ErrProcessDisabled   = errors.New("进程已被禁用")
+	ErrUnknownAction     = errors.New("未知操作")
+	ErrInvalidSignal     = errors.New("无效的信号")
+	ErrStdinClosed       = errors.New("标准输入未打开")
+	ErrProcessLocked     = errors.New("进程已在其他位置运行")
+)
+
+// processError 带有错误类别的进程操作错误
+type processError struct {
+	kind    error
+	message string
+}
+
+func (e *processError) Error() string { return e.message }
+
+func (e *processError) Unwrap() error { return e.kind }
+
+// newProcessError 创建带有错误类别的进程操作错误
+func newProcessError(kind error, format string, args ...interface{}) error {
+	return &processError{kind: kind, message: fmt.Sprintf(format, args...)}
 }
 
 // ProcessInfo 进程运行信息
@@ -63,6 +247,18 @@ type ProcessInfo struct {
 	Cmd     *exec.Cmd
 	Cancel  context.CancelFunc
 	Context context.Context
+	Done    chan struct{} // 进程退出（Wait 返回）后关闭，forking 类型在守护进程退出后关闭
+	Cleaned chan struct{} // post_stop 执行完成（未配置时在 Done 之后立即）关闭
+
+	DaemonPID atomic.Int64 // forking 类型从 PID 文件读取到的守护进程 PID
+
+	AdoptedPID int            // 从上次分离的 keeper 接管的进程 PID，此时 Cmd 为空
+	Outputs    []*outputTail  // 分离模式下读取标准输出和标准错误文件的协程
+	Pump       *logPump       // 把进程输出批量写入日志缓冲的协程
+	Files      *outputFileSet // stdout_file、stderr_file 打开的文件，进程退出后关闭，未配置时为空
+	OwnGroup   bool           // 进程是否为进程组组长，为 false 时信号只发给进程本身
+	Stdin      *processStdin  // 子进程标准输入管道，未配置标准输入时为空
+	Lock       *processLock   // lock_file 上持有的锁，进程退出后释放，未配置时为空
 }
 
 // ProcessManager 进程管理器
@@ -72,15 +268,58 @@ type ProcessManager struct {
 	mutex        sync.RWMutex
 	config       *Config
 	configPath   string
-	lastModified time.Time
+	configFormat string // 标准输入或 URL 配置的格式（json 或 yaml），为空时自动推断
+	profile      string // 选中的配置 profile，为空时只使用基础配置
+	fingerprint  string // 已加载的配置文件及其修改时间，用于判断是否需要重新加载
+	overrides    ServerOverrides
+	runner       Runner     // 创建子进程命令，测试时可替换
+	loadMutex    sync.Mutex // 串行化 LoadConfig
+	lastReload   ReloadResult
+	web          *webServer // Web 服务，启动监听后设置，重新加载配置时按需重新绑定
+	restarts     *restartLimiter
+	apiLimiter   *rateLimiter
+	logMutex     sync.Mutex                 // 保护所有进程的 Output、outputBytes 及以下日志字段，需同时持有时先获取 mutex
+	outputs      map[string]*ProcessStatus  // 仍在配置中的进程，与 processes 同步维护
+	outputHeap   outputHeap                 // outputs 中的进程按日志缓冲字节数排列，用于淘汰
+	subscribers  map[string]logSubscribers  // 实时日志（/api/process/{name}/tail）的订阅者，按进程名称
+	watchers     map[string]*processWatcher // 配置了 watch 的进程的文件监视
+	pending      []pendingStart             // 因达到 max_concurrent 上限排队等待启动的进程
+	logBytes     int                        // 所有进程日志缓冲的总字节数
+	logLayout    string                     // 进程日志时间戳格式
+	maxLogBytes  int                        // 所有进程日志缓冲的总字节数上限
+	ready        atomic.Bool                // 初始配置已加载且启用的进程已完成首次启动
 }
 
 // NewProcessManager 创建新的进程管理器
-func NewProcessManager(configPath string) *ProcessManager {
+func NewProcessManager(configPath string, overrides ServerOverrides) *ProcessManager {
 	return &ProcessManager{
-		processes:  make(map[string]*ProcessStatus),
-		commands:   make(map[string]*ProcessInfo),
-		configPath: configPath,
+		processes:   make(map[string]*ProcessStatus),
+		commands:    make(map[string]*ProcessInfo),
+		outputs:     make(map[string]*ProcessStatus),
+		subscribers: make(map[string]logSubscribers),
+		watchers:    make(map[string]*processWatcher),
+		configPath:  configPath,
+		overrides:   overrides,
+		runner:      execRunner{},
+		restarts:    newRestartLimiter(),
+		apiLimiter:  newRateLimiter(),
+	}
+}
+
+// applyOverrides 使用命令行参数覆盖服务器配置
+func (pm *ProcessManager) applyOverrides(config *Config) {
+	// 命令行指定了 host 或 port 时只监听该地址，忽略配置文件中的 listen
+	if pm.overrides.Host != "" || pm.overrides.Port != "" {
+		config.Server.Listen = nil
+	}
+	if pm.overrides.Host != "" {
+		config.Server.Host = pm.overrides.Host
+	}
+	if pm.overrides.Port != "" {
+		config.Server.Port = pm.overrides.Port
+	}
+	if pm.overrides.LogLevel != "" {
+		config.Server.LogLevel = pm.overrides.LogLevel
 	}
 }
 
@@ -110,84 +349,222 @@ func getDefaultConfig() *Config {
 	}
 }
 
-// LoadConfig 加载配置
-func (pm *ProcessManager) LoadConfig() error {
-	// 检查配置文件是否存在
-	if _, err := os.Stat(pm.configPath); os.IsNotExist(err) {
-		log.Printf("配置文件 %s 不存在，创建默认配置", pm.configPath)
-		return pm.createDefaultConfig()
-	}
+// LoadConfig 加载配置。读取、合并和校验都在局部变量上进行，
+// 任何一步失败都直接返回，当前的配置、进程状态和 fingerprint 保持不变，只有全部成功才替换
+func (pm *ProcessManager) LoadConfig() (err error) {
+	// 定期检查与手动重新加载可能同时进行，串行执行，避免较早读取的配置覆盖较新的
+	pm.loadMutex.Lock()
+	defer pm.loadMutex.Unlock()
+	defer func() { pm.recordReload(err) }()
 
-	// 检查文件是否被修改
-	fileInfo, err := os.Stat(pm.configPath)
-	if err != nil {
-		return fmt.Errorf("无法获取配置文件信息: %v", err)
-	}
+	var config Config
+	var digest string
+	if isStdinConfig(pm.configPath) || isURLConfig(pm.configPath) {
+		// 标准输入只能读取一次，不支持重新加载
+		if isStdinConfig(pm.configPath) && pm.config != nil {
+			return fmt.Errorf("从标准输入读取的配置不支持重新加载")
+		}
 
-	// 如果文件未被修改，且已加载过配置，则跳过
-	if !fileInfo.ModTime().After(pm.lastModified) && pm.config != nil {
-		return nil
+		// URL 配置每次都重新获取，内容和被包含的文件都未变化时在下面跳过
+		var err error
+		digest, err = readConfigSource(pm.configPath, pm.configFormat, &config)
+		if err != nil {
+			return err
+		}
+	} else {
+		// 检查配置文件是否存在，只在首次加载时创建默认配置；
+		// 重新加载时文件可能正在被编辑器替换，不能用默认配置覆盖正在运行的进程
+		if _, err := os.Stat(pm.configPath); os.IsNotExist(err) {
+			if pm.config != nil {
+				return fmt.Errorf("配置文件 %s 不存在", pm.configPath)
+			}
+			logInfof("配置文件 %s 不存在，创建默认配置", pm.configPath)
+			return pm.createDefaultConfig()
+		}
+
+		// 如果主配置文件和被包含的文件都未被修改，且已加载过配置，则跳过
+		if pm.config != nil {
+			files, err := resolveIncludes(pm.configPath, pm.config.Include)
+			if err == nil {
+				fingerprint, err := configFingerprint(append([]string{pm.configPath}, files...))
+				if err == nil && fingerprint == pm.fingerprint {
+					return nil
+				}
+			}
+		}
+
+		// 读取配置文件
+		if err := readConfigFile(pm.configPath, &config); err != nil {
+			return err
+		}
 	}
 
-	// 读取配置文件
-	data, err := os.ReadFile(pm.configPath)
+	files, err := pm.mergeConfig(&config)
 	if err != nil {
-		return fmt.Errorf("读取配置文件失败: %v", err)
+		return err
 	}
 
-	var config Config
-	ext := strings.ToLower(filepath.Ext(pm.configPath))
-
-	switch ext {
-	case ".json":
-		err = json.Unmarshal(data, &config)
-	case ".yaml", ".yml":
-		err = yaml.Unmarshal(data, &config)
-	default:
-		return fmt.Errorf("不支持的配置文件格式: %s，支持 .json, .yaml, .yml", ext)
+	var fingerprint string
+	if digest != "" {
+		fingerprint, err = configFingerprint(files)
+		if err != nil {
+			return err
+		}
+		fingerprint = digest + ";" + fingerprint
+		if pm.config != nil && fingerprint == pm.fingerprint {
+			return nil
+		}
+	} else {
+		fingerprint, err = configFingerprint(append([]string{pm.configPath}, files...))
+		if err != nil {
+			return err
+		}
 	}
 
-	if err != nil {
-		return fmt.Errorf("解析配置文件失败: %v", err)
-	}
+	pm.applyOverrides(&config)
 
 	// 验证配置
 	if err := pm.validateConfig(&config); err != nil {
 		return fmt.Errorf("配置验证失败: %v", err)
 	}
 
+	level, _ := parseLogLevel(config.Server.LogLevel)
+	setLogLevel(level)
+
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 
+	// 监听地址变化时重新绑定，旧地址上的请求（可能正是这次重新加载请求）需要先处理完，因此不在这里等待
+	if pm.web != nil && pm.config != nil && serverListenChanged(pm.config.Server, config.Server) {
+		pm.web.applyAsync(config.Server)
+	}
+
 	pm.config = &config
-	pm.lastModified = fileInfo.ModTime()
+	pm.fingerprint = fingerprint
+	pm.applyLogSettings(config.Server)
 
 	// 更新进程配置
+	configured := make(map[string]bool, len(config.Processes))
 	for _, processConfig := range config.Processes {
+		configured[processConfig.Name] = true
 		if existing, exists := pm.processes[processConfig.Name]; exists {
 			// 更新现有进程配置
-			existing.Config = processConfig
+			pm.updateProcessConfig(existing, processConfig)
 		} else {
 			// 添加新进程
-			pm.processes[processConfig.Name] = &ProcessStatus{
-				Config: processConfig,
-				Status: "stopped",
-				Output: make([]string, 0, 50),
-			}
+			pm.addProcess(processConfig)
+		}
+	}
+
+	// 移除配置中已删除的进程，仍在运行的进程会被停止
+	for name := range pm.processes {
+		if configured[name] {
+			continue
+		}
+		if procInfo, running := pm.commands[name]; running {
+			procInfo.Cancel()
+			logInfof("进程 %s 已从配置中移除，正在停止", name)
 		}
+		pm.removeProcess(name)
+		logInfof("进程 %s 已从配置中移除", name)
+	}
+
+	// 提前检查可执行文件和工作目录，结果保存在进程状态中供界面提示
+	pm.runPreflightLocked()
+	pm.syncWatchers()
+
+	// max_concurrent 可能被调大，尝试启动排队中的进程
+	go pm.startPending()
+
+	logInfof("配置加载成功，管理 %d 个进程", len(config.Processes))
+	return nil
+}
+
+// mergeConfig 合并 include 中的进程配置和选中的 profile，返回被包含的文件
+func (pm *ProcessManager) mergeConfig(config *Config) ([]string, error) {
+	// 重名由 validateConfig 统一检查
+	files, err := resolveIncludes(pm.configPath, config.Include)
+	if err != nil {
+		return nil, err
+	}
+	included, err := loadIncludedProcesses(files)
+	if err != nil {
+		return nil, err
+	}
+	config.Processes = append(config.Processes, included...)
+
+	// 合并选中 profile 的进程配置，包括对 include 中进程的覆盖
+	if err := applyProfile(config, pm.profile); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// updateProcessConfig 更新已有进程的配置
+// 只替换配置部分，PID、状态、启动时间、重启计数和输出等运行时状态保持不变
+func (pm *ProcessManager) updateProcessConfig(existing *ProcessStatus, processConfig ProcessConfig) {
+	existing.Config = processConfig
+
+	// 因重启次数过多被禁用的进程，重新加载配置后仍保持禁用自动重启，
+	// 需要通过"启用重启"显式恢复
+	if existing.Status == "disabled" {
+		existing.Config.AutoRestart = false
+	}
+}
+
+// readConfigFile 按扩展名读取并解析 JSON 或 YAML 配置文件
+func readConfigFile(path string, config *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %v", err)
+	}
+
+	var format string
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		format = "json"
+	case ".yaml", ".yml":
+		format = "yaml"
+	default:
+		return fmt.Errorf("不支持的配置文件格式: %s，支持 .json, .yaml, .yml", ext)
 	}
+	return parseConfig(data, format, path, config)
+}
 
-	log.Printf("配置加载成功，管理 %d 个进程", len(config.Processes))
+// parseConfig 替换环境变量后按格式解析配置内容，source 用于错误信息。
+// 替换配置中引用的环境变量，避免把密码等敏感信息写进配置文件
+func parseConfig(data []byte, format, source string, config *Config) error {
+	var expandErr, parseErr error
+	if format == "json" {
+		data, expandErr = expandJSONEnv(data)
+		if expandErr == nil {
+			parseErr = json.Unmarshal(data, config)
+		}
+	} else {
+		expandErr, parseErr = decodeYAMLConfig(data, config)
+	}
+	if expandErr != nil {
+		return fmt.Errorf("配置文件 %s 中%v", source, expandErr)
+	}
+	if parseErr != nil {
+		return fmt.Errorf("解析配置文件 %s 失败: %v", source, parseErr)
+	}
 	return nil
 }
 
 // createDefaultConfig 创建默认配置文件
 func (pm *ProcessManager) createDefaultConfig() error {
 	config := getDefaultConfig()
+	pm.applyOverrides(config)
 	pm.config = config
 
+	level, err := parseLogLevel(config.Server.LogLevel)
+	if err != nil {
+		return err
+	}
+	setLogLevel(level)
+
 	var data []byte
-	var err error
 
 	ext := strings.ToLower(filepath.Ext(pm.configPath))
 	switch ext {
@@ -216,35 +593,115 @@ func (pm *ProcessManager) createDefaultConfig() error {
 		return fmt.Errorf("写入默认配置文件失败: %v", err)
 	}
 
-	log.Printf("已创建默认配置文件: %s", pm.configPath)
+	logInfof("已创建默认配置文件: %s", pm.configPath)
 
 	// 初始化进程状态
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 
+	pm.applyLogSettings(config.Server)
 	for _, processConfig := range config.Processes {
-		pm.processes[processConfig.Name] = &ProcessStatus{
-			Config: processConfig,
-			Status: "stopped",
-			Output: make([]string, 0, 50),
-		}
+		pm.addProcess(processConfig)
 	}
 
 	return nil
 }
 
+// addProcess 添加新进程的状态，调用者需持有 mutex
+func (pm *ProcessManager) addProcess(processConfig ProcessConfig) {
+	status := &ProcessStatus{
+		Config:    processConfig,
+		Status:    "stopped",
+		Output:    make([]string, 0, 50),
+		counters:  &ioCounters{},
+		heapIndex: -1,
+	}
+	pm.processes[processConfig.Name] = status
+
+	pm.logMutex.Lock()
+	if old, exists := pm.outputs[processConfig.Name]; exists {
+		pm.clearOutput(old)
+		pm.untrackOutput(old)
+	}
+	pm.outputs[processConfig.Name] = status
+	pm.trackOutput(status)
+	pm.logMutex.Unlock()
+}
+
+// removeProcess 删除进程状态并释放其日志缓冲，调用者需持有 mutex
+func (pm *ProcessManager) removeProcess(name string) {
+	pm.logMutex.Lock()
+	if status, exists := pm.outputs[name]; exists {
+		pm.clearOutput(status)
+		pm.untrackOutput(status)
+		delete(pm.outputs, name)
+		pm.closeSubscribers(name)
+	}
+	pm.logMutex.Unlock()
+
+	delete(pm.processes, name)
+}
+
+// applyLogSettings 更新日志缓冲使用的配置
+func (pm *ProcessManager) applyLogSettings(server ServerConfig) {
+	pm.logMutex.Lock()
+	defer pm.logMutex.Unlock()
+
+	pm.logLayout = resolveLogTimeFormat(server.LogTimeFormat)
+	pm.maxLogBytes = server.MaxTotalLogBytes
+}
+
+// validateListen 验证监听地址配置，未配置 unix_socket 时补全默认的 host 和 port
+func validateListen(server *ServerConfig) error {
+	if server.UnixSocket != "" {
+		if server.Port != "" || server.Host != "" || len(server.Listen) > 0 {
+			return fmt.Errorf("unix_socket 与 host/port/listen 不能同时配置")
+		}
+		return nil
+	}
+	if server.Port == "" {
+		server.Port = "8080"
+	}
+	if server.Host == "" {
+		server.Host = "0.0.0.0"
+	}
+	_, err := listenAddresses(*server)
+	return err
+}
+
 // validateConfig 验证配置
 func (pm *ProcessManager) validateConfig(config *Config) error {
 	// 验证服务器配置
-	if config.Server.Port == "" {
-		config.Server.Port = "8080"
-	}
-	if config.Server.Host == "" {
-		config.Server.Host = "0.0.0.0"
+	if err := validateListen(&config.Server); err != nil {
+		return err
 	}
 	if config.Server.RefreshTime <= 0 {
 		config.Server.RefreshTime = 10
 	}
+	if _, err := parseLogLevel(config.Server.LogLevel); err != nil {
+		return err
+	}
+	if config.Server.RateLimit < 0 || config.Server.ControlRateLimit < 0 || config.Server.RateLimitBurst < 0 {
+		return fmt.Errorf("rate_limit、control_rate_limit 和 rate_limit_burst 不能为负数")
+	}
+	if config.Server.StartupStagger < 0 {
+		return fmt.Errorf("startup_stagger 不能为负数")
+	}
+	if config.Server.MaxConcurrent < 0 {
+		return fmt.Errorf("max_concurrent 不能为负数")
+	}
+	if config.Server.RestartJitter < 0 || config.Server.RestartJitter > 1 {
+		return fmt.Errorf("restart_jitter 必须在 0 到 1 之间")
+	}
+	if config.Server.MaxLineLength < 0 {
+		return fmt.Errorf("max_line_length 不能为负数")
+	}
+	if config.Server.DetachOutputMaxSize < 0 {
+		return fmt.Errorf("detach_output_max_size 不能为负数")
+	}
+	if config.Server.ReadTimeout < 0 || config.Server.WriteTimeout < 0 || config.Server.IdleTimeout < 0 {
+		return fmt.Errorf("read_timeout、write_timeout、idle_timeout 不能为负数")
+	}
 
 	// 验证进程配置
 	processNames := make(map[string]bool)
@@ -271,36 +728,213 @@ func (pm *ProcessManager) validateConfig(config *Config) error {
 		if processConfig.WorkDir == "" {
 			config.Processes[i].WorkDir = "."
 		}
+		if processConfig.RestartWindow < 0 {
+			return fmt.Errorf("进程[%s] restart_window 不能为负数", processConfig.Name)
+		}
+		if processConfig.RestartCountResetAfter < 0 {
+			return fmt.Errorf("进程[%s] restart_count_reset_after 不能为负数", processConfig.Name)
+		}
+		if processConfig.Interval < 0 {
+			return fmt.Errorf("进程[%s] interval 不能为负数", processConfig.Name)
+		}
+		if processConfig.Interval > 0 && processConfig.Type != "" && processConfig.Type != ProcessTypeSimple {
+			return fmt.Errorf("进程[%s] interval 仅支持 simple 类型", processConfig.Name)
+		}
+		if _, err := parseUmask(processConfig.Umask); err != nil {
+			return fmt.Errorf("进程[%s] umask 无效: %v", processConfig.Name, err)
+		}
+		if processConfig.MaxRuntime < 0 {
+			return fmt.Errorf("进程[%s] max_runtime 不能为负数", processConfig.Name)
+		}
+		if processConfig.MinRestartInterval < 0 {
+			return fmt.Errorf("进程[%s] min_restart_interval 不能为负数", processConfig.Name)
+		}
+		if processConfig.SilenceTimeout < 0 {
+			return fmt.Errorf("进程[%s] silence_timeout 不能为负数", processConfig.Name)
+		}
+		if err := validateStopSequence(processConfig.StopSequence); err != nil {
+			return fmt.Errorf("进程[%s] stop_sequence 无效: %v", processConfig.Name, err)
+		}
+		if processConfig.WatchDebounce < 0 {
+			return fmt.Errorf("进程[%s] watch_debounce 不能为负数", processConfig.Name)
+		}
+		if processConfig.HookTimeout < 0 {
+			return fmt.Errorf("进程[%s] hook_timeout 不能为负数", processConfig.Name)
+		}
+		if processConfig.StdinData != "" && processConfig.StdinFile != "" {
+			return fmt.Errorf("进程[%s] stdin_data 和 stdin_file 不能同时配置", processConfig.Name)
+		}
+		if _, err := parseOutputFileMode(processConfig.OutputFileMode); err != nil {
+			return fmt.Errorf("进程[%s] %v", processConfig.Name, err)
+		}
+		if err := validateOrphanPolicy(processConfig.OnOrphan); err != nil {
+			return fmt.Errorf("进程[%s] %v", processConfig.Name, err)
+		}
+		if err := validateMaxRestartsPolicy(processConfig.OnMaxRestarts); err != nil {
+			return fmt.Errorf("进程[%s] %v", processConfig.Name, err)
+		}
+		if processConfig.SlowRetryInterval < 0 {
+			return fmt.Errorf("进程[%s] slow_retry_interval 不能为负数", processConfig.Name)
+		}
+		if err := validateResourceAlert(processConfig.Alert); err != nil {
+			return fmt.Errorf("进程[%s] %v", processConfig.Name, err)
+		}
+		if processConfig.OutputFileMaxSize < 0 {
+			return fmt.Errorf("进程[%s] output_file_max_size 不能为负数", processConfig.Name)
+		}
+		if processConfig.OutputFileMaxBackups < 0 {
+			return fmt.Errorf("进程[%s] output_file_max_backups 不能为负数", processConfig.Name)
+		}
+		if _, err := parseWorkDirMode(processConfig.WorkDirMode); err != nil {
+			return fmt.Errorf("进程[%s] workdir_mode 无效: %v", processConfig.Name, err)
+		}
+		for _, tag := range processConfig.Tags {
+			if tag == "" || strings.Contains(tag, "/") {
+				return fmt.Errorf("进程[%s] 标签无效: %q", processConfig.Name, tag)
+			}
+		}
+		if resolved, err := resolvePlaceholders(processConfig, pm.configPath); err != nil {
+			logWarnf("警告: 进程[%s]占位符无效: %v", processConfig.Name, err)
+		} else if !processConfig.CreateWorkDir && resolved.WorkDir != "" {
+			if _, err := os.Stat(resolved.WorkDir); os.IsNotExist(err) {
+				logWarnf("警告: 进程[%s]工作目录 %s 不存在，且未启用 create_workdir", processConfig.Name, resolved.WorkDir)
+			}
+		}
+		if _, err := regexp.Compile(processConfig.LogFilter); err != nil {
+			return fmt.Errorf("进程[%s] log_filter 无效: %v", processConfig.Name, err)
+		}
+		if _, err := regexp.Compile(processConfig.LogHighlight); err != nil {
+			return fmt.Errorf("进程[%s] log_highlight 无效: %v", processConfig.Name, err)
+		}
+
+		switch processConfig.Type {
+		case "":
+			config.Processes[i].Type = ProcessTypeSimple
+		case ProcessTypeSimple, ProcessTypeOneshot:
+		case ProcessTypeForking:
+			if processConfig.PIDFile == "" {
+				return fmt.Errorf("进程[%s]类型为 forking 时必须配置 pidfile", processConfig.Name)
+			}
+		default:
+			return fmt.Errorf("进程[%s]类型无效: %s，支持 simple, forking, oneshot", processConfig.Name, processConfig.Type)
+		}
+
+		switch processConfig.ProcessGroup {
+		case "":
+			config.Processes[i].ProcessGroup = ProcessGroupOwn
+		case ProcessGroupOwn, ProcessGroupSession, ProcessGroupInherit:
+		default:
+			return fmt.Errorf("进程[%s] process_group 无效: %s，支持 own, session, inherit", processConfig.Name, processConfig.ProcessGroup)
+		}
+	}
+
+	// 严格模式下检查所有可执行文件是否存在
+	if config.Server.StrictValidation {
+		var missing []string
+		for _, processConfig := range config.Processes {
+			resolved, err := resolvePlaceholders(processConfig, pm.configPath)
+			if err != nil {
+				missing = append(missing, fmt.Sprintf("进程[%s]: %v", processConfig.Name, err))
+				continue
+			}
+			resolved = applyShell(resolved)
+			if err := pm.checkExecutable(resolved.Command); err != nil {
+				missing = append(missing, fmt.Sprintf("进程[%s]: %v", processConfig.Name, err))
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("以下进程的可执行文件不可用: %s", strings.Join(missing, "; "))
+		}
+	}
+
+	return nil
+}
+
+// checkExecutable 检查命令对应的可执行文件是否存在，PATH 查找通过 runner 进行
+func (pm *ProcessManager) checkExecutable(command string) error {
+	if filepath.IsAbs(command) {
+		if _, err := os.Stat(command); os.IsNotExist(err) {
+			return fmt.Errorf("可执行文件不存在: %s", command)
+		}
+		return nil
 	}
 
+	// 如果不是绝对路径，在 PATH 中查找
+	if _, err := pm.runner.LookPath(command); err != nil {
+		return fmt.Errorf("命令不存在: %s", command)
+	}
 	return nil
 }
 
 // StartProcess 启动进程
 func (pm *ProcessManager) StartProcess(name string) error {
+	return pm.startProcess(name, false)
+}
+
+// ForceStartProcess 强制启动进程，忽略 Enabled 检查且不修改配置中的 Enabled
+func (pm *ProcessManager) ForceStartProcess(name string) error {
+	return pm.startProcess(name, true)
+}
+
+// startProcess 启动进程，force 为 true 时允许启动未启用的进程
+func (pm *ProcessManager) startProcess(name string, force bool) error {
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 
 	status, exists := pm.processes[name]
 	if !exists {
-		return fmt.Errorf("进程 %s 不存在", name)
+		return newProcessError(ErrProcessNotFound, "进程 %s 不存在", name)
 	}
 
-	if status.Status == "running" {
-		return fmt.Errorf("进程 %s 已经在运行", name)
+	if status.Status == "running" || status.Status == "starting" || status.Status == "paused" {
+		return newProcessError(ErrProcessRunning, "进程 %s 已经在运行", name)
 	}
 
-	if !status.Config.Enabled {
-		return fmt.Errorf("进程 %s 已被禁用", name)
+	if status.Status == "pending" {
+		return newProcessError(ErrProcessRunning, "进程 %s 已在排队等待启动", name)
+	}
+
+	forced := force && !status.Config.Enabled
+	if !status.Config.Enabled && !forced {
+		return newProcessError(ErrProcessDisabled, "进程 %s 已被禁用", name)
+	}
+
+	// 距上次启动不足 min_restart_interval 时排队，到时间后再启动
+	if wait := restartThrottle(status, time.Now()); wait > 0 {
+		pm.throttleStart(name, status, force, wait)
+		return nil
+	}
+
+	// 运行中的进程达到 max_concurrent 上限时排队，有进程退出后再启动
+	if pm.concurrencyLimitReached() {
+		pm.queueStart(name, status, forced)
+		return nil
 	}
 
-	config := status.Config
+	return pm.launchProcess(name, status, forced)
+}
+
+// launchProcess 实际启动进程，调用者需持有锁并已完成运行状态和启用检查
+func (pm *ProcessManager) launchProcess(name string, status *ProcessStatus, forced bool) error {
+	// 先标记为 starting，之后的每条失败路径都会改为 error 或 disabled。
+	// 启动过程中暂时释放锁（如执行 pre_start）时，并发的启动请求会因此被拒绝，不会启动两个进程
+	status.Status = "starting"
+
+	// 替换 command、args 和 workdir 中的占位符，失败时不启动
+	config, err := resolvePlaceholders(status.Config, pm.configPath)
+	if err != nil {
+		status.Status = "error"
+		status.LastError = fmt.Sprintf("占位符替换失败: %v", err)
+		pm.addLog(name, fmt.Sprintf("ERROR: 占位符替换失败: %v", err))
+		return fmt.Errorf("占位符替换失败: %v", err)
+	}
+	config = applyShell(config)
 
 	// 检查可执行文件是否存在
 	execPath := config.Command
 	if !filepath.IsAbs(execPath) {
 		// 如果不是绝对路径，在 PATH 中查找
-		if _, err := exec.LookPath(execPath); err != nil {
+		if _, err := pm.runner.LookPath(execPath); err != nil {
 			status.Status = "error"
 			status.LastError = fmt.Sprintf("命令不存在: %s", execPath)
 			pm.addLog(name, fmt.Sprintf("ERROR: 命令不存在: %s", execPath))
@@ -315,34 +949,73 @@ func (pm *ProcessManager) StartProcess(name string) error {
 		}
 	}
 
-	// 检查重启次数限制
-	if status.Restarts >= config.MaxRestarts {
+	// 检查工作目录，按需创建
+	if config.WorkDir != "" {
+		if _, err := os.Stat(config.WorkDir); os.IsNotExist(err) {
+			if !config.CreateWorkDir {
+				status.Status = "error"
+				status.LastError = fmt.Sprintf("工作目录不存在: %s", config.WorkDir)
+				pm.addLog(name, fmt.Sprintf("ERROR: 工作目录不存在: %s", config.WorkDir))
+				return fmt.Errorf("工作目录不存在: %s", config.WorkDir)
+			}
+
+			mode, _ := parseWorkDirMode(config.WorkDirMode)
+			if err := os.MkdirAll(config.WorkDir, mode); err != nil {
+				status.Status = "error"
+				status.LastError = fmt.Sprintf("创建工作目录失败: %v", err)
+				pm.addLog(name, fmt.Sprintf("ERROR: 创建工作目录失败: %v", err))
+				return fmt.Errorf("创建工作目录失败: %v", err)
+			}
+			pm.addLog(name, fmt.Sprintf("INFO: 已创建工作目录 %s", config.WorkDir))
+		}
+	}
+
+	// 检查重启次数限制，on_max_restarts 为 slow-retry 或 alert-only 时继续启动
+	if disablesOnMaxRestarts(status.Config) && restartLimitReached(status, time.Now()) {
+		pm.alertMaxRestarts(name, status)
 		status.Status = "disabled"
+		status.StopReason = StopReasonDisabled
 		status.Config.AutoRestart = false
 		pm.addLog(name, fmt.Sprintf("ERROR: 重启次数过多 (%d次)，已禁用自动重启", status.Restarts))
-		return fmt.Errorf("进程 %s 重启次数过多，已禁用", name)
+		return newProcessError(ErrProcessDisabled, "进程 %s 重启次数过多，已禁用", name)
+	}
+
+	// 执行 pre_start 前置检查，失败时不启动进程
+	if config.PreStart != "" {
+		if err := pm.runPreStart(name, status, config); err != nil {
+			return err
+		}
+	}
+
+	// 配置了 lock_file 时先加锁，锁被其他 keeper 或进程持有说明任务已在其他位置运行
+	lock, err := pm.acquireLock(config)
+	if err != nil {
+		status.Status = "error"
+		status.LastError = err.Error()
+		pm.addLog(name, fmt.Sprintf("ERROR: %v", err))
+		if errors.Is(err, ErrProcessLocked) {
+			return err
+		}
+		return fmt.Errorf("启动进程 %s 失败: %v", name, err)
 	}
 
 	// 创建上下文用于进程控制
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// 构建命令
+	// 构建命令，是否需要 sudo 按原始命令判断
 	var cmd *exec.Cmd
+	launch := applyUmask(config)
 	if needsSudo(config.Command, config.User) {
 		// 使用 sudo 启动
-		args := buildSudoArgs(config)
-		cmd = exec.CommandContext(ctx, "sudo", args...)
+		args := buildSudoArgs(launch)
+		cmd = pm.runner.CommandContext(ctx, "sudo", args...)
 	} else {
-		// 过滤掉空参数
-		var filteredArgs []string
-		for _, arg := range config.Args {
-			if arg != "" {
-				filteredArgs = append(filteredArgs, arg)
-			}
-		}
-		cmd = exec.CommandContext(ctx, config.Command, filteredArgs...)
+		cmd = pm.runner.CommandContext(ctx, launch.Command, buildArgs(launch)...)
 	}
 
+	// 记录实际执行的命令行，便于排查 sudo 包装或参数处理问题
+	status.CommandLine = append([]string(nil), cmd.Args...)
+
 	// 设置工作目录
 	if config.WorkDir != "" {
 		cmd.Dir = config.WorkDir
@@ -356,21 +1029,111 @@ func (pm *ProcessManager) StartProcess(name string) error {
 		}
 		cmd.Env = env
 	}
+	if cmd.Env != nil {
+		status.environment = cmd.Env
+	} else {
+		status.environment = os.Environ()
+	}
 
 	// 设置进程组，便于管理子进程
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Setpgid: true,
-		Pgid:    0,
+	cmd.SysProcAttr = processSysProcAttr(config.ProcessGroup)
+	ownGroup := ownsProcessGroup(config.ProcessGroup)
+
+	// 取消上下文时向整个进程组发送 stop_sequence 第一步的信号（默认 SIGTERM，exec 默认是 SIGKILL），
+	// 让进程在 StopProcess 的等待时间内有机会清理退出
+	stopSig := stopSignal(config)
+	cmd.Cancel = func() error {
+		err := signalProcess(cmd.Process.Pid, stopSig, ownGroup)
+		if err == syscall.ESRCH {
+			return os.ErrProcessDone
+		}
+		return err
+	}
+	// forking 类型的守护进程通常会继承启动命令的输出管道，启动命令退出后不再等待管道关闭，
+	// 否则 Wait 要等到守护进程退出才返回。之后守护进程的输出不再被捕获
+	if config.Type == ProcessTypeForking {
+		cmd.WaitDelay = launcherWaitDelay
 	}
 
 	// 捕获输出
-	cmd.Stdout = &logWriter{name: name, pm: pm, isStdout: true}
-	cmd.Stderr = &logWriter{name: name, pm: pm, isStdout: false}
+	filter := compilePattern(config.LogFilter)
+	secrets := secretValues(config.Environment, effectiveSecretKeys(pm.config.Server))
+	pump := pm.newLogPump(name)
+	maxLine := maxLineLength(pm.config.Server)
+	stdout := &logWriter{name: name, pm: pm, isStdout: true, filter: filter, secrets: secrets, pump: pump, maxLine: maxLine, counter: &status.counters.stdout}
+	stderr := &logWriter{name: name, pm: pm, isStdout: false, filter: filter, secrets: secrets, pump: pump, maxLine: maxLine, counter: &status.counters.stderr}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	// 配置了 stdout_file、stderr_file 时输出同时写入这些文件
+	files, err := pm.openOutputFiles(config, false)
+	if err != nil {
+		cancel()
+		pump.Close()
+		lock.Release()
+		status.Status = "error"
+		status.LastError = err.Error()
+		pm.addLog(name, fmt.Sprintf("ERROR: %v", err))
+		return fmt.Errorf("启动进程 %s 失败: %v", name, err)
+	}
+	files.attach(stdout, stderr)
+
+	// 配置了 stdin_data、stdin_file 或 stdin_open 时通过管道提供标准输入
+	stdin, stdinData, err := pm.openStdin(config, cmd, &status.counters.stdin)
+	if err != nil {
+		cancel()
+		pump.Close()
+		lock.Release()
+		files.Close()
+		status.Status = "error"
+		status.LastError = err.Error()
+		pm.addLog(name, fmt.Sprintf("ERROR: %v", err))
+		return fmt.Errorf("启动进程 %s 失败: %v", name, err)
+	}
+
+	// 分离模式下子进程直接写入输出文件，由 keeper 读取转发到日志
+	var outputFiles []*os.File
+	if pm.config.Server.DetachOnExit {
+		outputFiles, err = pm.redirectOutput(name, cmd)
+		if err != nil {
+			cancel()
+			pump.Close()
+			lock.Release()
+			files.Close()
+			if stdin != nil {
+				stdin.Close()
+			}
+			status.Status = "error"
+			status.LastError = err.Error()
+			pm.addLog(name, fmt.Sprintf("ERROR: %v", err))
+			return fmt.Errorf("启动进程 %s 失败: %v", name, err)
+		}
+	}
+	// 子进程继承锁文件描述符，keeper 退出（分离或崩溃）后锁仍由进程持有，直到其退出，
+	// 避免新的 keeper 在进程仍运行时再启动一份
+	if lock != nil {
+		cmd.ExtraFiles = append(cmd.ExtraFiles, lock.file)
+	}
 
-	// 启动进程
-	err := cmd.Start()
+	// 启动进程，启动耗时从这里开始计算，到写入协程收到第一行输出为止
+	status.StartupDuration = 0
+	pump.startedAt = time.Now()
+	err = cmd.Start()
+	if config.Umask != "" {
+		mask, _ := parseUmask(config.Umask)
+		pm.addLog(name, fmt.Sprintf("INFO: 使用 umask %04o 启动", mask))
+	}
+	for _, file := range outputFiles {
+		file.Close()
+	}
 	if err != nil {
 		cancel()
+		pump.Close()
+		lock.Release()
+		files.Close()
+		if stdin != nil {
+			stdin.Close()
+		}
 		status.Status = "error"
 		status.LastError = err.Error()
 		pm.addLog(name, fmt.Sprintf("ERROR: 启动失败: %v", err))
@@ -379,24 +1142,122 @@ func (pm *ProcessManager) StartProcess(name string) error {
 
 	// 保存进程信息
 	pm.commands[name] = &ProcessInfo{
-		Cmd:     cmd,
-		Cancel:  cancel,
-		Context: ctx,
+		Cmd:      cmd,
+		Cancel:   cancel,
+		Context:  ctx,
+		Done:     make(chan struct{}),
+		Cleaned:  make(chan struct{}),
+		Pump:     pump,
+		Files:    files,
+		OwnGroup: ownGroup,
+		Stdin:    stdin,
+		Lock:     lock,
+	}
+	lock.writePID(cmd.Process.Pid)
+	if stdin != nil {
+		stdin.started(name, stdinData, config.StdinOpen)
+	}
+	if outputFiles != nil {
+		pm.commands[name].Outputs = pm.tailOutputs(name, stdout, stderr, 0, 0)
 	}
 
 	status.PID = cmd.Process.Pid
 	status.Status = "running"
 	status.StartTime = time.Now()
+	pm.recordRunning()
 	status.LastError = ""
+	status.LogWriteError = ""
+	status.NextRunTime = time.Time{}
+	status.StopReason = ""
 
 	pm.addLog(name, fmt.Sprintf("INFO: 进程启动成功，PID: %d", status.PID))
+	if forced {
+		pm.addLog(name, "INFO: 强制手动启动，进程未启用，退出后不会自动重启")
+	}
 
-	// 监控进程状态
-	go pm.monitorProcess(name)
+	// 启动保护期内保持 starting 状态，存活超过保护期才视为启动成功（oneshot 类型不适用）
+	if config.StartupGrace > 0 && config.Type != ProcessTypeOneshot {
+		status.Status = "starting"
+		go pm.confirmStartup(name, pm.commands[name], config.StartupGrace)
+	}
 
-	log.Printf("进程 %s 启动成功，PID: %d", name, status.PID)
-	return nil
-}
+	// 运行超过 max_runtime 后停止
+	if config.MaxRuntime > 0 {
+		go pm.enforceMaxRuntime(name, pm.commands[name], config.MaxRuntime)
+	}
+
+	// 持续运行足够长时间后自动重置重启计数
+	if config.RestartCountResetAfter > 0 {
+		go pm.resetRestartsAfterUptime(name, pm.commands[name], config.RestartCountResetAfter)
+	}
+
+	// 配置了 alert 时采样 CPU 和内存占用
+	if config.Alert.enabled() {
+		go pm.monitorResources(name, pm.commands[name], config)
+	}
+
+	// 监控进程状态
+	go pm.monitorProcess(name)
+
+	logInfof("进程 %s 启动成功，PID: %d", name, status.PID)
+	return nil
+}
+
+// applyShell shell 模式下改为通过 shell -c 执行 command，args 作为位置参数 $1、$2...（$0 为进程名称）
+func applyShell(config ProcessConfig) ProcessConfig {
+	if !config.Shell {
+		return config
+	}
+	shell := config.ShellPath
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	args := []string{"-c", config.Command, config.Name}
+	config.Args = append(args, config.Args...)
+	config.Command = shell
+	return config
+}
+
+// applyUmask 配置了 umask 时改为由 /bin/sh 在子进程中设置 umask 后 exec 原命令。
+// umask 是进程级属性，不能在 keeper 中临时修改：其他协程同时创建的文件（日志、状态文件等）也会受影响
+func applyUmask(config ProcessConfig) ProcessConfig {
+	mask, err := parseUmask(config.Umask)
+	if err != nil || mask < 0 {
+		return config
+	}
+	args := []string{"-c", fmt.Sprintf(`umask %04o; exec "$0" "$@"`, mask), config.Command}
+	config.Args = append(args, buildArgs(config)...)
+	config.Command = "/bin/sh"
+	config.TrimEmptyArgs = false
+	return config
+}
+
+// parseUmask 解析八进制的 umask，为空时返回 -1
+func parseUmask(umask string) (int, error) {
+	if umask == "" {
+		return -1, nil
+	}
+	value, err := strconv.ParseUint(umask, 8, 32)
+	if err != nil {
+		return 0, err
+	}
+	if value > 0777 {
+		return 0, fmt.Errorf("超出范围: %s", umask)
+	}
+	return int(value), nil
+}
+
+// parseWorkDirMode 解析八进制的工作目录权限，为空时返回 0755
+func parseWorkDirMode(mode string) (os.FileMode, error) {
+	if mode == "" {
+		return 0755, nil
+	}
+	value, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return 0, err
+	}
+	return os.FileMode(value) & os.ModePerm, nil
+}
 
 // buildSudoArgs 构建 sudo 命令参数
 func buildSudoArgs(config ProcessConfig) []string {
@@ -410,61 +1271,108 @@ func buildSudoArgs(config ProcessConfig) []string {
 	args = append(args, config.Command)
 
 	// 添加进程参数
+	args = append(args, buildArgs(config)...)
+
+	return args
+}
+
+// buildArgs 构建进程参数
+// 空字符串是合法参数（例如 sed -i "" 中的空字符串），仅在配置 trim_empty_args 时丢弃
+func buildArgs(config ProcessConfig) []string {
+	if !config.TrimEmptyArgs {
+		return config.Args
+	}
+
+	var filteredArgs []string
 	for _, arg := range config.Args {
 		if arg != "" {
-			args = append(args, arg)
+			filteredArgs = append(filteredArgs, arg)
 		}
 	}
-
-	return args
+	return filteredArgs
 }
 
 // StopProcess 停止进程
 func (pm *ProcessManager) StopProcess(name string) error {
 	pm.mutex.Lock()
-	defer pm.mutex.Unlock()
 
 	status, exists := pm.processes[name]
 	if !exists {
-		return fmt.Errorf("进程 %s 不存在", name)
+		pm.mutex.Unlock()
+		return newProcessError(ErrProcessNotFound, "进程 %s 不存在", name)
+	}
+
+	// 排队等待启动的进程，停止即取消排队
+	if status.Status == "pending" {
+		pm.cancelPending(name)
+		status.Status = "stopped"
+		status.StopReason = StopReasonManual
+		pm.addLog(name, "INFO: 已取消排队")
+		logInfof("进程 %s 已取消排队", name)
+		pm.mutex.Unlock()
+		return nil
+	}
+
+	// 周期进程在等待下一次运行时，停止即取消后续调度
+	if status.Status == "waiting" {
+		status.Status = "stopped"
+		status.StopReason = StopReasonManual
+		status.NextRunTime = time.Time{}
+		pm.addLog(name, "INFO: 已取消周期运行")
+		logInfof("进程 %s 已取消周期运行", name)
+		pm.mutex.Unlock()
+		return nil
 	}
 
 	procInfo, cmdExists := pm.commands[name]
-	if !cmdExists || status.Status != "running" {
-		return fmt.Errorf("进程 %s 没有运行", name)
+	if !cmdExists || (status.Status != "running" && status.Status != "starting" && status.Status != "paused") {
+		pm.mutex.Unlock()
+		return newProcessError(ErrProcessNotRunning, "进程 %s 没有运行", name)
 	}
 
 	pm.addLog(name, "INFO: 正在停止进程...")
 
-	// 取消上下文
+	// 取消上下文，由 cmd.Cancel 发送 stop_sequence 第一步的信号
+	steps := stopSteps(status.Config)
 	procInfo.Cancel()
 
-	// 给进程一些时间优雅退出
-	done := make(chan error, 1)
-	go func() {
-		done <- procInfo.Cmd.Wait()
-	}()
+	// forking 类型的守护进程不在启动命令的进程组中，需要单独通知
+	if daemonPID := int(procInfo.DaemonPID.Load()); daemonPID > 0 {
+		syscall.Kill(daemonPID, steps[0].signal)
+	}
 
-	// 等待 5 秒，如果还没退出就强制杀死
-	select {
-	case <-done:
-		// 进程已经退出
-	case <-time.After(5 * time.Second):
-		// 超时，强制杀死进程组
-		if procInfo.Cmd.Process != nil {
-			syscall.Kill(-procInfo.Cmd.Process.Pid, syscall.SIGKILL)
-			<-done // 等待 Wait() 完成
-		}
-		pm.addLog(name, "WARNING: 进程未在 5 秒内退出，已强制终止")
+	// 暂停的进程收到 SIGTERM 后要恢复运行才能处理
+	if status.Status == "paused" {
+		signalProcess(status.PID, syscall.SIGCONT, procInfo.OwnGroup)
+	}
+
+	// 等待期间释放锁，monitorProcess 需要获取锁来完成退出处理
+	pm.mutex.Unlock()
+
+	// 给进程一些时间优雅退出，Wait() 由 monitorProcess 负责调用；
+	// 按 stop_sequence 逐步发送信号，全部步骤后仍未退出就强制杀死
+	forceKilled, waited := pm.awaitStop(name, procInfo, steps)
+	<-procInfo.Cleaned // 等待 post_stop 执行完成，最长为 hook_timeout
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	if forceKilled {
+		pm.addLog(name, fmt.Sprintf("WARNING: 进程未在 %d 秒内退出，已强制终止", waited))
 	}
 
+	// 等待期间进程已被重新启动
+	if current, exists := pm.commands[name]; exists && current != procInfo {
+		return nil
+	}
 	delete(pm.commands, name)
 
 	status.Status = "stopped"
+	status.StopReason = StopReasonManual
 	status.PID = 0
 
 	pm.addLog(name, "INFO: 进程已手动停止")
-	log.Printf("进程 %s 已停止", name)
+	logInfof("进程 %s 已停止", name)
 	return nil
 }
 
@@ -472,7 +1380,7 @@ func (pm *ProcessManager) StopProcess(name string) error {
 func (pm *ProcessManager) RestartProcess(name string) error {
 	// 先停止进程
 	err := pm.StopProcess(name)
-	if err != nil && !strings.Contains(err.Error(), "没有运行") {
+	if err != nil && !errors.Is(err, ErrProcessNotRunning) {
 		return err
 	}
 
@@ -488,6 +1396,250 @@ func (pm *ProcessManager) RestartProcess(name string) error {
 	return pm.StartProcess(name)
 }
 
+// processAction 对单个进程执行启动、停止或重启操作
+func (pm *ProcessManager) processAction(name, action string) (string, error) {
+	switch action {
+	case "start":
+		if err := pm.StartProcess(name); err != nil {
+			return "", err
+		}
+		if message := pm.deferredStartMessage(name); message != "" {
+			return message, nil
+		}
+		return fmt.Sprintf("进程 %s 启动成功", name), nil
+	case "stop":
+		return fmt.Sprintf("进程 %s 停止成功", name), pm.StopProcess(name)
+	case "restart":
+		if err := pm.RestartProcess(name); err != nil {
+			return "", err
+		}
+		if message := pm.deferredStartMessage(name); message != "" {
+			return message, nil
+		}
+		return fmt.Sprintf("进程 %s 重启成功", name), nil
+	case "pause":
+		return fmt.Sprintf("进程 %s 已暂停", name), pm.PauseProcess(name)
+	case "resume":
+		return fmt.Sprintf("进程 %s 已恢复", name), pm.ResumeProcess(name)
+	case "recover":
+		if err := pm.EnableAutoRestart(name); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("进程 %s 已重新启用并启动", name), pm.StartProcess(name)
+	case "reset-restarts":
+		return fmt.Sprintf("进程 %s 的重启计数已重置", name), pm.ResetRestarts(name)
+	default:
+		return "", newProcessError(ErrUnknownAction, "未知操作: %s", action)
+	}
+}
+
+// deferredStartMessage 启动请求被排队（max_concurrent）或推迟（min_restart_interval）时返回提示，否则返回空
+func (pm *ProcessManager) deferredStartMessage(name string) string {
+	status, exists := pm.GetProcess(name)
+	if !exists {
+		return ""
+	}
+	switch status.Status {
+	case "pending":
+		return fmt.Sprintf("进程 %s 已排队等待启动", name)
+	case "waiting":
+		return fmt.Sprintf("进程 %s 将于 %s 启动", name, status.NextRunTime.Format("15:04:05"))
+	}
+	return ""
+}
+
+// BulkResult 批量操作中单个进程的执行结果
+type BulkResult struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// bulkWorkers 批量操作的最大并发数
+const bulkWorkers = 4
+
+// checkBulkAction 检查批量操作是否受支持
+func checkBulkAction(action string) error {
+	switch action {
+	case "start", "stop", "restart":
+		return nil
+	default:
+		return newProcessError(ErrUnknownAction, "未知操作: %s", action)
+	}
+}
+
+// ApplyToAll 对所有启用的进程并发执行同一操作，单个进程失败不会中断其他进程
+func (pm *ProcessManager) ApplyToAll(action string) (map[string]BulkResult, error) {
+	if err := checkBulkAction(action); err != nil {
+		return nil, err
+	}
+
+	pm.mutex.RLock()
+	var names []string
+	for name, status := range pm.processes {
+		if status.Config.Enabled {
+			names = append(names, name)
+		}
+	}
+	pm.mutex.RUnlock()
+
+	return pm.runBulk(names, action), nil
+}
+
+// ApplyToGroup 对带有指定标签的所有启用进程并发执行同一操作
+func (pm *ProcessManager) ApplyToGroup(tag, action string) (map[string]BulkResult, error) {
+	if err := checkBulkAction(action); err != nil {
+		return nil, err
+	}
+
+	pm.mutex.RLock()
+	var names []string
+	for name, status := range pm.processes {
+		if status.Config.Enabled && slices.Contains(status.Config.Tags, tag) {
+			names = append(names, name)
+		}
+	}
+	pm.mutex.RUnlock()
+
+	if len(names) == 0 {
+		return nil, newProcessError(ErrProcessNotFound, "没有标签为 %s 的启用进程", tag)
+	}
+	return pm.runBulk(names, action), nil
+}
+
+// RecoverProcesses 重新启用处于 error 或 disabled 状态的所有进程，重置重启计数后并发启动
+func (pm *ProcessManager) RecoverProcesses() map[string]BulkResult {
+	pm.mutex.RLock()
+	var names []string
+	for name, status := range pm.processes {
+		if status.Status == "error" || status.Status == "disabled" {
+			names = append(names, name)
+		}
+	}
+	pm.mutex.RUnlock()
+
+	if len(names) > 0 {
+		logInfof("恢复 %d 个出错或被禁用的进程", len(names))
+	}
+	return pm.runBulk(names, "recover")
+}
+
+// runBulk 使用有界工作池并发执行操作并汇总结果
+func (pm *ProcessManager) runBulk(names []string, action string) map[string]BulkResult {
+	results := make(map[string]BulkResult, len(names))
+	var resultsMutex sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, bulkWorkers)
+
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			message, err := pm.processAction(name, action)
+			result := BulkResult{Success: err == nil, Message: message}
+			if err != nil {
+				result.Message = ""
+				result.Error = err.Error()
+			}
+
+			resultsMutex.Lock()
+			results[name] = result
+			resultsMutex.Unlock()
+		}(name)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// SignalProcess 向运行中的进程发送信号，group 为 true 时发送给整个进程组
+func (pm *ProcessManager) SignalProcess(name string, sig syscall.Signal, group bool) error {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	status, exists := pm.processes[name]
+	if !exists {
+		return newProcessError(ErrProcessNotFound, "进程 %s 不存在", name)
+	}
+	procInfo, running := pm.commands[name]
+	if !running || status.PID == 0 || (status.Status != "running" && status.Status != "starting" && status.Status != "paused") {
+		return newProcessError(ErrProcessNotRunning, "进程 %s 没有运行", name)
+	}
+
+	// forking 类型的 PID 是守护进程的 PID
+	pid := status.PID
+
+	if err := signalProcess(pid, sig, group && procInfo.OwnGroup); err != nil {
+		return fmt.Errorf("向进程 %s 发送信号失败: %v", name, err)
+	}
+
+	pm.addLog(name, fmt.Sprintf("INFO: 已发送信号 %s", signalName(sig)))
+	logInfof("已向进程 %s (PID: %d) 发送信号 %s", name, pid, signalName(sig))
+	return nil
+}
+
+// PauseProcess 向进程组发送 SIGSTOP 暂停进程，进程保持存活，不会被视为退出
+func (pm *ProcessManager) PauseProcess(name string) error {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	status, exists := pm.processes[name]
+	if !exists {
+		return newProcessError(ErrProcessNotFound, "进程 %s 不存在", name)
+	}
+	if status.Status == "paused" {
+		return newProcessError(ErrProcessNotRunning, "进程 %s 已经暂停", name)
+	}
+	procInfo, running := pm.commands[name]
+	if !running || status.PID == 0 || (status.Status != "running" && status.Status != "starting") {
+		return newProcessError(ErrProcessNotRunning, "进程 %s 没有运行", name)
+	}
+
+	if err := signalProcess(status.PID, syscall.SIGSTOP, procInfo.OwnGroup); err != nil {
+		return fmt.Errorf("暂停进程 %s 失败: %v", name, err)
+	}
+
+	status.Status = "paused"
+	pm.addLog(name, "INFO: 进程已暂停")
+	logInfof("进程 %s 已暂停", name)
+	return nil
+}
+
+// ResumeProcess 向进程组发送 SIGCONT 恢复暂停的进程
+func (pm *ProcessManager) ResumeProcess(name string) error {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	status, exists := pm.processes[name]
+	if !exists {
+		return newProcessError(ErrProcessNotFound, "进程 %s 不存在", name)
+	}
+	procInfo, running := pm.commands[name]
+	if !running || status.Status != "paused" {
+		return newProcessError(ErrProcessNotRunning, "进程 %s 没有暂停", name)
+	}
+
+	if err := signalProcess(status.PID, syscall.SIGCONT, procInfo.OwnGroup); err != nil {
+		return fmt.Errorf("恢复进程 %s 失败: %v", name, err)
+	}
+
+	status.Status = "running"
+	pm.addLog(name, "INFO: 进程已恢复运行")
+	logInfof("进程 %s 已恢复运行", name)
+	return nil
+}
+
+// signalName 返回信号名称，未知信号返回编号
+func signalName(sig syscall.Signal) string {
+	if name, ok := signalNames[sig]; ok {
+		return name
+	}
+	return fmt.Sprintf("信号 %d", int(sig))
+}
+
 // EnableAutoRestart 启用自动重启
 func (pm *ProcessManager) EnableAutoRestart(name string) error {
 	pm.mutex.Lock()
@@ -495,441 +1647,1355 @@ func (pm *ProcessManager) EnableAutoRestart(name string) error {
 
 	status, exists := pm.processes[name]
 	if !exists {
-		return fmt.Errorf("进程 %s 不存在", name)
+		return newProcessError(ErrProcessNotFound, "进程 %s 不存在", name)
 	}
 
 	status.Config.AutoRestart = true
 	status.Config.Enabled = true
-	status.Restarts = 0 // 重置重启计数
+	resetRestarts(status, time.Now())
+	pm.syncWatchers()
 	if status.Status == "disabled" {
 		status.Status = "stopped"
+		status.StopReason = ""
 	}
 
 	pm.addLog(name, "INFO: 已启用自动重启并重置重启计数")
 	return nil
 }
 
-// monitorProcess 监控进程状态
-func (pm *ProcessManager) monitorProcess(name string) {
-	pm.mutex.RLock()
-	procInfo, exists := pm.commands[name]
+// ResetRestarts 只重置重启计数，被禁用的进程恢复为 stopped，不修改 auto_restart 和 enabled
+func (pm *ProcessManager) ResetRestarts(name string) error {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	status, exists := pm.processes[name]
 	if !exists {
-		pm.mutex.RUnlock()
-		return
+		return newProcessError(ErrProcessNotFound, "进程 %s 不存在", name)
+	}
+
+	resetRestarts(status, time.Now())
+	if status.Status == "disabled" {
+		status.Status = "stopped"
+		status.StopReason = ""
 	}
-	cmd := procInfo.Cmd
-	pm.mutex.RUnlock()
 
-	err := cmd.Wait()
+	pm.addLog(name, "INFO: 已重置重启计数")
+	return nil
+}
 
+// ClearLogs 清空进程的日志缓冲，以及 stdout_file、stderr_file 和分离模式的输出文件（轮转文件保留）
+func (pm *ProcessManager) ClearLogs(name string) error {
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 
-	status := pm.processes[name]
-	delete(pm.commands, name)
-
-	// 获取退出状态码
-	exitCode := 0
-	if err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			exitCode = exitError.ExitCode()
-		}
-
-		// 如果是被取消的上下文，说明是正常停止
-		if err == context.Canceled {
-			pm.addLog(name, "INFO: 进程正常停止")
-			log.Printf("进程 %s 正常停止", name)
-		} else {
-			status.LastError = err.Error()
-			pm.addLog(name, fmt.Sprintf("ERROR: 进程异常退出: %v (退出码: %d)", err, exitCode))
-			log.Printf("进程 %s 异常退出: %v (退出码: %d)", name, err, exitCode)
-		}
-	} else {
-		pm.addLog(name, "INFO: 进程正常退出")
-		log.Printf("进程 %s 正常退出", name)
+	status, exists := pm.processes[name]
+	if !exists {
+		return newProcessError(ErrProcessNotFound, "进程 %s 不存在", name)
 	}
 
-	status.Status = "stopped"
-	status.PID = 0
-	status.LastExitCode = exitCode
+	pm.logMutex.Lock()
+	pm.clearOutput(status)
+	pm.logMutex.Unlock()
 
-	// 只有在异常退出时才增加重启计数
-	if err != nil && err != context.Canceled {
-		status.Restarts++
+	// 同时清空 stdout_file、stderr_file 和分离模式的输出文件，否则日志搜索和下载仍能看到旧内容
+	var files *outputFileSet
+	var tails []*outputTail
+	if procInfo, running := pm.commands[name]; running {
+		files, tails = procInfo.Files, procInfo.Outputs
+	}
+	config, err := resolvePlaceholders(status.Config, pm.configPath)
+	if err == nil {
+		err = pm.truncateOutputFiles(config, files)
+	}
+	if err == nil {
+		err = pm.truncateDetachOutputs(name, tails)
+	}
+	if err != nil {
+		logWarnf("清空进程 %s 的输出文件失败: %v", name, err)
+		return fmt.Errorf("日志缓冲已清空，但清空输出文件失败: %v", err)
+	}
+	logInfof("已清空进程 %s 的日志", name)
+	return nil
+}
 
-		// 如果重启次数过多，禁用自动重启
-		if status.Restarts >= status.Config.MaxRestarts {
-			log.Printf("进程 %s 重启次数过多(%d次)，禁用自动重启", name, status.Restarts)
-			status.Config.AutoRestart = false
-			status.Status = "disabled"
-			pm.addLog(name, fmt.Sprintf("WARNING: 重启次数过多 (%d次)，已禁用自动重启", status.Restarts))
-			return
-		}
+// confirmStartup 启动保护期结束后仍在运行，则将进程标记为 running
+func (pm *ProcessManager) confirmStartup(name string, procInfo *ProcessInfo, grace int) {
+	select {
+	case <-procInfo.Done:
+		// 保护期内退出，由 monitorProcess 处理
+		return
+	case <-time.After(time.Duration(grace) * time.Second):
+	}
 
-		// 自动重启
-		if status.Config.AutoRestart && status.Config.Enabled {
-			restartDelay := status.Config.RestartDelay
-			pm.addLog(name, fmt.Sprintf("INFO: %d秒后自动重启 (第%d次重启)", restartDelay, status.Restarts))
-			log.Printf("%d秒后自动重启进程 %s (第%d次重启)", restartDelay, name, status.Restarts)
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
 
-			// 使用 goroutine 避免阻塞
-			go func() {
-				time.Sleep(time.Duration(restartDelay) * time.Second)
-				err := pm.StartProcess(name)
-				if err != nil {
-					log.Printf("自动重启进程 %s 失败: %v", name, err)
-				}
-			}()
-		}
+	status, exists := pm.processes[name]
+	if !exists || pm.commands[name] != procInfo || status.Status != "starting" {
+		return
 	}
+
+	status.Status = "running"
+	pm.addLog(name, fmt.Sprintf("INFO: 进程已稳定运行 %d 秒，启动成功", grace))
 }
 
-// addLog 添加日志
-func (pm *ProcessManager) addLog(name, message string) {
-	if status, exists := pm.processes[name]; exists {
-		logLine := fmt.Sprintf("[%s] %s", time.Now().Format("15:04:05"), message)
-		status.Output = append(status.Output, logLine)
-		if len(status.Output) > 50 {
-			status.Output = status.Output[1:]
-		}
+// resetRestartsAfterUptime 进程连续运行超过指定秒数后重置重启计数
+func (pm *ProcessManager) resetRestartsAfterUptime(name string, procInfo *ProcessInfo, uptime int) {
+	select {
+	case <-procInfo.Done:
+		return
+	case <-time.After(time.Duration(uptime) * time.Second):
 	}
-}
 
-// logWriter 用于捕获进程输出
-type logWriter struct {
-	name     string
-	pm       *ProcessManager
-	isStdout bool
-}
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
 
-func (lw *logWriter) Write(p []byte) (n int, err error) {
-	line := strings.TrimSpace(string(p))
-	if line == "" {
-		return len(p), nil
+	status, exists := pm.processes[name]
+	if !exists || pm.commands[name] != procInfo || status.Status != "running" || status.Restarts == 0 {
+		return
 	}
 
-	lw.pm.mutex.Lock()
-	defer lw.pm.mutex.Unlock()
-
-	if status, exists := lw.pm.processes[lw.name]; exists {
-		// 添加时间戳和类型标识
-		prefix := "STDOUT"
-		if !lw.isStdout {
-			prefix = "STDERR"
-		}
-		logLine := fmt.Sprintf("[%s] %s: %s", time.Now().Format("15:04:05"), prefix, line)
+	pm.addLog(name, fmt.Sprintf("INFO: 进程已连续运行 %d 秒，重置重启计数（之前为 %d 次）", uptime, status.Restarts))
+	logInfof("进程 %s 已连续运行 %d 秒，重置重启计数（之前为 %d 次）", name, uptime, status.Restarts)
+	resetRestarts(status, time.Now())
+}
 
-		// 保留最近 50 行输出
-		status.Output = append(status.Output, logLine)
-		if len(status.Output) > 50 {
-			status.Output = status.Output[1:]
-		}
+// enforceMaxRuntime 进程运行超过 max_runtime 秒后停止，进程提前退出时取消。
+// oneshot 任务记为失败，周期进程照常调度下一次运行
+func (pm *ProcessManager) enforceMaxRuntime(name string, procInfo *ProcessInfo, seconds int) {
+	timer := time.NewTimer(time.Duration(seconds) * time.Second)
+	defer timer.Stop()
 
-		// 也记录到主日志
-		log.Printf("进程 %s %s: %s", lw.name, prefix, line)
+	select {
+	case <-procInfo.Done:
+		return
+	case <-timer.C:
+	}
+
+	pm.mutex.Lock()
+	if _, exists := pm.processes[name]; !exists || pm.commands[name] != procInfo {
+		pm.mutex.Unlock()
+		return
+	}
+	pm.addLog(name, fmt.Sprintf("WARNING: 运行超过 max_runtime（%d秒），停止进程", seconds))
+	logWarnf("进程 %s 运行超过 max_runtime（%d秒），停止进程", name, seconds)
+	pm.mutex.Unlock()
+
+	if err := pm.StopProcess(name); err != nil {
+		logErrorf("停止超时进程 %s 失败: %v", name, err)
+		return
+	}
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	// 停止后进程可能已被重新启动
+	status, exists := pm.processes[name]
+	if !exists || status.Status != "stopped" || status.StopReason != StopReasonManual {
+		return
+	}
+
+	status.StopReason = StopReasonTimeout
+	status.LastError = fmt.Sprintf("运行超过 max_runtime（%d秒）", seconds)
+	switch {
+	case status.Config.Type == ProcessTypeOneshot:
+		status.Status = "failed"
+		pm.addLog(name, "ERROR: 一次性任务运行超时")
+	case status.Config.Interval > 0:
+		pm.scheduleNextRun(name, status)
+	}
+}
+
+// scheduleNextRun 记录周期进程本次运行结果，并在间隔后再次运行
+func (pm *ProcessManager) scheduleNextRun(name string, status *ProcessStatus) {
+	now := time.Now()
+	status.LastDuration = now.Sub(status.StartTime).Seconds()
+	if !status.Config.Enabled {
+		return
+	}
+
+	interval := time.Duration(status.Config.Interval) * time.Second
+	nextRun := now.Add(interval)
+	status.Status = "waiting"
+	status.StopReason = StopReasonScheduled
+	status.NextRunTime = nextRun
+	pm.addLog(name, fmt.Sprintf("INFO: 本次运行耗时 %.1f 秒，%d秒后再次运行", status.LastDuration, status.Config.Interval))
+
+	go func() {
+		time.Sleep(interval)
+
+		// 等待期间被手动停止、启动或从配置中移除时，放弃本次调度
+		pm.mutex.RLock()
+		current, exists := pm.processes[name]
+		scheduled := exists && current.Status == "waiting" && current.NextRunTime.Equal(nextRun)
+		pm.mutex.RUnlock()
+		if !scheduled {
+			return
+		}
+
+		if err := pm.StartProcess(name); err != nil && !errors.Is(err, ErrProcessRunning) {
+			logErrorf("周期运行进程 %s 失败: %v", name, err)
+		}
+	}()
+}
+
+// restartThrottle 返回按 min_restart_interval 还需等待多久才能再次启动，不需要等待时返回 0
+func restartThrottle(status *ProcessStatus, now time.Time) time.Duration {
+	if status.Config.MinRestartInterval <= 0 || status.StartTime.IsZero() {
+		return 0
+	}
+	return status.StartTime.Add(time.Duration(status.Config.MinRestartInterval) * time.Second).Sub(now)
+}
+
+// throttleStart 把过于频繁的启动推迟到 min_restart_interval 结束，期间状态为 waiting，调用者需持有锁。
+// 重复的启动请求只保留最新的一次
+func (pm *ProcessManager) throttleStart(name string, status *ProcessStatus, force bool, wait time.Duration) {
+	nextRun := time.Now().Add(wait)
+	status.Status = "waiting"
+	status.NextRunTime = nextRun
+	pm.addLog(name, fmt.Sprintf("INFO: 距上次启动不足 %d 秒，%.1f 秒后启动", status.Config.MinRestartInterval, wait.Seconds()))
+	logInfof("进程 %s 距上次启动不足 %d 秒，%.1f 秒后启动", name, status.Config.MinRestartInterval, wait.Seconds())
+
+	go func() {
+		time.Sleep(wait)
+
+		// 等待期间被手动停止、重新调度或从配置中移除时，放弃本次启动
+		pm.mutex.RLock()
+		current, exists := pm.processes[name]
+		scheduled := exists && current.Status == "waiting" && current.NextRunTime.Equal(nextRun)
+		pm.mutex.RUnlock()
+		if !scheduled {
+			return
+		}
+
+		if err := pm.startProcess(name, force); err != nil && !errors.Is(err, ErrProcessRunning) {
+			logErrorf("延迟启动进程 %s 失败: %v", name, err)
+		}
+	}()
+}
+
+// signalNames 常见信号名称
+var signalNames = map[syscall.Signal]string{
+	syscall.SIGHUP:   "SIGHUP",
+	syscall.SIGINT:   "SIGINT",
+	syscall.SIGQUIT:  "SIGQUIT",
+	syscall.SIGILL:   "SIGILL",
+	syscall.SIGABRT:  "SIGABRT",
+	syscall.SIGBUS:   "SIGBUS",
+	syscall.SIGFPE:   "SIGFPE",
+	syscall.SIGKILL:  "SIGKILL",
+	syscall.SIGSEGV:  "SIGSEGV",
+	syscall.SIGPIPE:  "SIGPIPE",
+	syscall.SIGTERM:  "SIGTERM",
+	syscall.SIGUSR1:  "SIGUSR1",
+	syscall.SIGUSR2:  "SIGUSR2",
+	syscall.SIGALRM:  "SIGALRM",
+	syscall.SIGCONT:  "SIGCONT",
+	syscall.SIGSTOP:  "SIGSTOP",
+	syscall.SIGTSTP:  "SIGTSTP",
+	syscall.SIGTTIN:  "SIGTTIN",
+	syscall.SIGTTOU:  "SIGTTOU",
+	syscall.SIGWINCH: "SIGWINCH",
+}
+
+// parseSignal 解析信号名称（如 HUP、SIGHUP）或编号
+func parseSignal(value string) (syscall.Signal, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return 0, newProcessError(ErrInvalidSignal, "未指定信号")
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		if n <= 0 || n > 64 {
+			return 0, newProcessError(ErrInvalidSignal, "无效的信号编号: %d", n)
+		}
+		return syscall.Signal(n), nil
+	}
+	if !strings.HasPrefix(value, "SIG") {
+		value = "SIG" + value
+	}
+	for sig, name := range signalNames {
+		if name == value {
+			return sig, nil
+		}
+	}
+	return 0, newProcessError(ErrInvalidSignal, "未知的信号: %s", value)
+}
+
+// describeSignal 生成进程被信号终止的说明
+func describeSignal(sig syscall.Signal) string {
+	name := signalName(sig)
+	switch sig {
+	case syscall.SIGKILL:
+		return fmt.Sprintf("被 %s 终止（可能是内存不足被 OOM killer 杀死）", name)
+	case syscall.SIGSEGV, syscall.SIGBUS:
+		return fmt.Sprintf("被 %s 终止（内存访问错误）", name)
+	case syscall.SIGABRT:
+		return fmt.Sprintf("被 %s 终止（进程异常中止）", name)
+	default:
+		return fmt.Sprintf("被 %s 终止", name)
+	}
+}
+
+// monitorProcess 监控进程状态
+func (pm *ProcessManager) monitorProcess(name string) {
+	pm.mutex.RLock()
+	procInfo, exists := pm.commands[name]
+	if !exists {
+		pm.mutex.RUnlock()
+		return
+	}
+	cmd := procInfo.Cmd
+	var config ProcessConfig
+	if status, exists := pm.processes[name]; exists {
+		config = status.Config
+	}
+	pm.mutex.RUnlock()
+
+	var err error
+	if cmd == nil {
+		// 从上次分离的 keeper 接管的进程不是当前 keeper 的子进程，只能轮询存活状态
+		err = pm.monitorAdopted(procInfo)
+	} else {
+		err = cmd.Wait()
+		// forking 类型的启动命令已正常退出，只是守护进程仍持有继承的输出管道
+		if errors.Is(err, exec.ErrWaitDelay) && config.Type == ProcessTypeForking {
+			err = nil
+		}
+
+		// Wait 返回后输出已全部复制完成，补上末尾没有换行的最后一行
+		for _, w := range []io.Writer{cmd.Stdout, cmd.Stderr} {
+			if lw, ok := w.(*logWriter); ok {
+				lw.Flush()
+			}
+		}
+	}
+
+	// 输出写入文件时，读完进程退出前写入的剩余内容
+	for _, tail := range procInfo.Outputs {
+		tail.Close()
+	}
+	procInfo.Files.Close()
+	// 等待已读取的输出写入日志缓冲，之后记录的退出信息才会排在最后
+	if procInfo.Pump != nil {
+		procInfo.Pump.Close()
+	}
+	if procInfo.Stdin != nil {
+		procInfo.Stdin.Close()
+	}
+
+	// forking 类型：启动命令正常退出后，改为监控 PID 文件中的守护进程
+	if err == nil && config.Type == ProcessTypeForking && procInfo.Context.Err() == nil {
+		err = pm.monitorForking(name, procInfo, config)
+	}
+	close(procInfo.Done)
+
+	// 进程退出后执行清理命令，StopProcess 等待其完成后才返回，避免与下一次启动重叠
+	if config.PostStop != "" {
+		pm.runPostStop(name, config)
+	}
+	procInfo.Lock.Release()
+	close(procInfo.Cleaned)
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	// 进程已被停止并重新启动，由新的监控协程负责
+	if current, exists := pm.commands[name]; exists && current != procInfo {
+		return
+	}
+
+	delete(pm.commands, name)
+	pm.recordRunning()
+
+	// 空出的名额留给排队等待启动的进程，协程在本函数释放锁后才会执行
+	go pm.startPending()
+
+	// 进程可能在运行期间被从配置中移除
+	status, exists := pm.processes[name]
+	if !exists {
+		logInfof("进程 %s 已退出（已从配置中移除）", name)
+		return
+	}
+
+	// 被信号终止时 Wait 返回的是 ExitError 而非 context.Canceled，
+	// 因此通过上下文状态判断是否为主动停止
+	stoppedByUser := procInfo.Context.Err() != nil
+
+	// 启动保护期内退出（即使退出码为 0）视为启动失败，计入重启次数
+	if status.Status == "starting" && !stoppedByUser {
+		pm.addLog(name, fmt.Sprintf("ERROR: 进程在启动保护期（%d秒）内退出，视为启动失败", status.Config.StartupGrace))
+		if err == nil {
+			err = fmt.Errorf("进程在启动保护期（%d秒）内退出", status.Config.StartupGrace)
+		}
+	}
+
+	// 获取退出状态码
+	exitCode := 0
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			exitCode = exitError.ExitCode()
+
+			// 被信号终止时 ExitCode 为 -1，按 shell 惯例记为 128+信号值，并记录具体信号
+			if ws, ok := exitError.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+				exitCode = 128 + int(ws.Signal())
+				err = errors.New(describeSignal(ws.Signal()))
+			}
+		} else if cmd == nil {
+			// 接管的进程无法获取退出状态
+			exitCode = -1
+		}
+
+		// 如果上下文已被取消，说明是主动停止
+		if stoppedByUser {
+			pm.addLog(name, "INFO: 进程正常停止")
+			logInfof("进程 %s 正常停止", name)
+		} else {
+			status.LastError = err.Error()
+			pm.addLog(name, fmt.Sprintf("ERROR: 进程异常退出: %v (退出码: %d)", err, exitCode))
+			logErrorf("进程 %s 异常退出: %v (退出码: %d)", name, err, exitCode)
+		}
+	} else {
+		pm.addLog(name, "INFO: 进程正常退出")
+		logInfof("进程 %s 正常退出", name)
+	}
+
+	status.Status = "stopped"
+	status.PID = 0
+	status.LastExitCode = exitCode
+	switch {
+	case stoppedByUser:
+		status.StopReason = StopReasonManual
+	case err != nil:
+		status.StopReason = StopReasonCrashed
+	default:
+		status.StopReason = StopReasonExited
+	}
+	if !stoppedByUser {
+		status.ExitCodeHistory = append(status.ExitCodeHistory, exitCode)
+		if len(status.ExitCodeHistory) > maxExitCodeHistory {
+			status.ExitCodeHistory = status.ExitCodeHistory[1:]
+		}
+	}
+
+	// oneshot 类型进程运行结束后只记录结果，不计入重启也不自动重启
+	if status.Config.Type == ProcessTypeOneshot && !stoppedByUser {
+		if err == nil {
+			status.Status = "completed"
+			pm.addLog(name, "INFO: 一次性任务执行完成")
+		} else {
+			status.Status = "failed"
+			pm.addLog(name, fmt.Sprintf("ERROR: 一次性任务执行失败 (退出码: %d)", exitCode))
+		}
+		return
+	}
+
+	// 周期进程运行结束后按间隔调度下一次运行，不计入重启次数
+	if status.Config.Interval > 0 && !stoppedByUser {
+		pm.scheduleNextRun(name, status)
+		return
+	}
+
+	// 只有在异常退出时才增加重启计数
+	if err != nil && !stoppedByUser {
+		status.Restarts++
+		recordRestart(status, time.Now())
+
+		restartDelay := time.Duration(status.Config.RestartDelay) * time.Second
+
+		// 重启次数过多时按 on_max_restarts 处理：默认禁用自动重启
+		if !restartLimitReached(status, time.Now()) {
+			status.restartAlerted = false
+		} else {
+			pm.alertMaxRestarts(name, status)
+			switch status.Config.OnMaxRestarts {
+			case MaxRestartsSlowRetry:
+				restartDelay = slowRetryInterval(status.Config)
+				pm.addLog(name, fmt.Sprintf("WARNING: 重启次数过多 (%d次)，改为每 %g 秒重试一次", status.Restarts, restartDelay.Seconds()))
+			case MaxRestartsAlertOnly:
+				pm.addLog(name, fmt.Sprintf("WARNING: 重启次数过多 (%d次)，按 on_max_restarts 继续重启", status.Restarts))
+			default:
+				logWarnf("进程 %s 重启次数过多(%d次)，禁用自动重启", name, status.Restarts)
+				status.Config.AutoRestart = false
+				status.Status = "disabled"
+				status.StopReason = StopReasonDisabled
+				pm.addLog(name, fmt.Sprintf("WARNING: 重启次数过多 (%d次)，已禁用自动重启", status.Restarts))
+				return
+			}
+		}
+
+		// 自动重启
+		if status.Config.AutoRestart && status.Config.Enabled {
+			restartDelay = jitterDelay(restartDelay, pm.config.Server.RestartJitter)
+			delaySeconds := float64(restartDelay.Milliseconds()) / 1000
+			pm.addLog(name, fmt.Sprintf("INFO: %g秒后自动重启 (第%d次重启)", delaySeconds, status.Restarts))
+			logInfof("%g秒后自动重启进程 %s (第%d次重启)", delaySeconds, name, status.Restarts)
+
+			// 使用 goroutine 避免阻塞
+			go func() {
+				time.Sleep(restartDelay)
+
+				pm.acquireRestart(name)
+				defer pm.restarts.release()
+
+				// 等待期间进程可能已被手动启动，此时无需重启
+				err := pm.StartProcess(name)
+				if errors.Is(err, ErrProcessRunning) {
+					logInfof("进程 %s 已在运行，跳过自动重启", name)
+				} else if err != nil {
+					logErrorf("自动重启进程 %s 失败: %v", name, err)
+				} else {
+					// 重启名额占用到进程完成启动为止，而不只是调用 StartProcess 的瞬间
+					pm.awaitStartup(name)
+				}
+			}()
+		}
+	}
+}
+
+// startupPollInterval 自动重启等待进程完成启动时检查状态的间隔
+const startupPollInterval = 100 * time.Millisecond
+
+// awaitStartup 等待进程离开 starting 状态：启动保护期（startup_grace）结束或期间退出。
+// 未配置 startup_grace 的进程启动后即为 running，立即返回；排队等待启动（pending）的进程也不等待
+func (pm *ProcessManager) awaitStartup(name string) {
+	pm.mutex.RLock()
+	procInfo := pm.commands[name]
+	pm.mutex.RUnlock()
+	if procInfo == nil {
+		return
+	}
+
+	ticker := time.NewTicker(startupPollInterval)
+	defer ticker.Stop()
+	for {
+		pm.mutex.RLock()
+		status, exists := pm.processes[name]
+		starting := exists && pm.commands[name] == procInfo && status.Status == "starting"
+		pm.mutex.RUnlock()
+		if !starting {
+			return
+		}
+
+		select {
+		case <-procInfo.Done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// acquireRestart 按全局限流配置等待自动重启名额
+func (pm *ProcessManager) acquireRestart(name string) {
+	pm.mutex.RLock()
+	var server ServerConfig
+	if pm.config != nil {
+		server = pm.config.Server
+	}
+	pm.mutex.RUnlock()
+
+	interval := time.Duration(server.RestartIntervalMs) * time.Millisecond
+	pm.restarts.acquire(server.MaxConcurrentRestarts, interval, func(reason string) {
+		pm.mutex.Lock()
+		pm.addLog(name, fmt.Sprintf("INFO: 自动重启等待中：%s", reason))
+		pm.mutex.Unlock()
+		logInfof("进程 %s 自动重启等待中：%s", name, reason)
+	})
+}
+
+// addLog 添加日志，调用方需持有 mutex
+func (pm *ProcessManager) addLog(name, message string) {
+	if status, exists := pm.processes[name]; exists {
+		pm.logMutex.Lock()
+		defer pm.logMutex.Unlock()
+
+		logLine := fmt.Sprintf("[%s] %s", pm.logTimestamp(), message)
+		pm.appendOutput(status, logLine)
+	}
+}
+
+// maxOutputLines 每个进程日志缓冲保留的行数
+const maxOutputLines = 50
+
+// appendOutput 追加一行到进程日志缓冲，超出行数或总字节上限时淘汰旧日志，调用方需持有 logMutex
+func (pm *ProcessManager) appendOutput(status *ProcessStatus, line string) {
+	status.Output = append(status.Output, line)
+	pm.resizeOutput(status, len(line))
+	pm.publishLog(status.Config.Name, line)
+
+	if len(status.Output) > maxOutputLines {
+		pm.dropOldestOutput(status)
+	}
+	pm.enforceLogBudget()
+}
+
+// dropOldestOutput 丢弃进程日志缓冲中最早的一行，调用方需持有 logMutex
+func (pm *ProcessManager) dropOldestOutput(status *ProcessStatus) {
+	size := len(status.Output[0])
+	status.Output = status.Output[1:]
+	pm.resizeOutput(status, -size)
+}
+
+// clearOutput 清空进程日志缓冲，调用方需持有 logMutex
+func (pm *ProcessManager) clearOutput(status *ProcessStatus) {
+	pm.resizeOutput(status, -status.outputBytes)
+	status.Output = nil
+}
+
+// recordRestart 记录一次重启事件，超出上限时丢弃最早的记录
+func recordRestart(status *ProcessStatus, at time.Time) {
+	// 窗口模式下需要至少保留 max_restarts 条记录才能正确计数
+	limit := max(maxRestartHistory, status.Config.MaxRestarts)
+	status.RestartHistory = append(status.RestartHistory, at)
+	if len(status.RestartHistory) > limit {
+		status.RestartHistory = status.RestartHistory[len(status.RestartHistory)-limit:]
+	}
+}
+
+// resetRestarts 重置重启计数，之前的重启历史不再计入滑动窗口
+func resetRestarts(status *ProcessStatus, at time.Time) {
+	status.Restarts = 0
+	status.restartsResetAt = at
+	status.restartAlerted = false
+}
+
+// recentRestarts 统计滑动窗口内（且在最近一次重置之后）的重启次数
+func recentRestarts(status *ProcessStatus, window time.Duration, now time.Time) int {
+	cutoff := now.Add(-window)
+	if status.restartsResetAt.After(cutoff) {
+		cutoff = status.restartsResetAt
+	}
+	count := 0
+	for _, at := range status.RestartHistory {
+		if at.After(cutoff) {
+			count++
+		}
+	}
+	return count
+}
+
+// restartLimitReached 判断是否达到重启次数上限
+// 配置了 restart_window 时按窗口内的重启频率判断，否则按累计重启次数判断
+func restartLimitReached(status *ProcessStatus, now time.Time) bool {
+	if status.Config.RestartWindow > 0 {
+		window := time.Duration(status.Config.RestartWindow) * time.Second
+		return recentRestarts(status, window, now) >= status.Config.MaxRestarts
+	}
+	return status.Restarts >= status.Config.MaxRestarts
+}
+
+// logTimestamp 按配置的格式生成进程日志时间戳，调用方需持有 logMutex
+func (pm *ProcessManager) logTimestamp() string {
+	return time.Now().Format(pm.logLayout)
+}
+
+// outputLines 返回进程日志缓冲的副本
+func (pm *ProcessManager) outputLines(status *ProcessStatus) []string {
+	pm.logMutex.Lock()
+	defer pm.logMutex.Unlock()
+	return slices.Clone(status.Output)
+}
+
+// logWriter 用于捕获进程输出
+type logWriter struct {
+	name       string
+	pm         *ProcessManager
+	isStdout   bool
+	filter     *regexp.Regexp // 不为空时只有匹配的行保留在日志缓冲中
+	partial    []byte         // 尚未遇到换行的不完整行，Write 由 exec 的单个复制协程调用，无需加锁
+	secrets    []string       // 需要从输出中抹除的敏感值
+	pump       *logPump       // 标准输出和标准错误共用，保持两者之间的先后顺序
+	sink       io.Writer      // 额外的输出目标（如日志文件），为空时只写入日志缓冲
+	sinkFailed bool           // 上一次写入 sink 是否失败
+	maxLine    int            // 单行最大字节数，超出部分截断后再写入日志缓冲
+	counter    *atomic.Int64  // 累计输出字节数，为空时不计数
+}
+
+// defaultMaxLineLength 未配置 max_line_length 时日志缓冲中单行输出的最大字节数
+const defaultMaxLineLength = 64 * 1024
+
+// truncatedLineMarker 截断的行末尾追加的标记
+const truncatedLineMarker = "…[已截断]"
+
+// maxLineLength 返回日志缓冲中单行输出的最大字节数
+func maxLineLength(server ServerConfig) int {
+	if server.MaxLineLength > 0 {
+		return server.MaxLineLength
+	}
+	return defaultMaxLineLength
+}
+
+// compilePattern 编译配置中的正则，空字符串返回 nil（配置加载时已校验）
+func compilePattern(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	return regexp.MustCompile(pattern)
+}
+
+// Write 始终返回 len(p), nil：向子进程返回错误会导致其输出管道阻塞或收到 SIGPIPE，
+// 写入额外目标失败时只记录到进程状态中
+func (lw *logWriter) Write(p []byte) (n int, err error) {
+	if lw.counter != nil {
+		lw.counter.Add(int64(len(p)))
+	}
+	if lw.sink != nil {
+		lw.writeSink(p)
+	}
+
+	// 一次写入可能包含多行，也可能只是一行的一部分，
+	// 按换行拆分，不完整的行留到下次写入或进程退出时处理
+	lw.partial = append(lw.partial, p...)
+	for {
+		i := bytes.IndexByte(lw.partial, '\n')
+		if i < 0 {
+			break
+		}
+		lw.writeLine(lw.partial[:i])
+		lw.partial = lw.partial[i+1:]
+	}
+
+	// 长时间没有换行时只保留 maxLine+1 字节（多出的一个字节用于判断需要截断），其余丢弃直到遇到换行，避免缓冲无限增长。
+	// 另外多保留最长敏感值的长度，保证跨过截断位置的敏感值完整，能在截断之前被抹除
+	if lw.maxLine > 0 {
+		limit := lw.maxLine + 1
+		for _, value := range lw.secrets {
+			limit = max(limit, lw.maxLine+len(value))
+		}
+		if len(lw.partial) > limit {
+			lw.partial = append([]byte(nil), lw.partial[:limit]...)
+		}
+	}
+	return len(p), nil
+}
+
+// clip 把超过 maxLine 字节的行截断（不拆分 UTF-8 字符）并追加截断标记
+func (lw *logWriter) clip(line string) string {
+	if lw.maxLine <= 0 || len(line) <= lw.maxLine {
+		return line
+	}
+	cut := lw.maxLine
+	for cut > 0 && !utf8.RuneStart(line[cut]) {
+		cut--
+	}
+	return line[:cut] + truncatedLineMarker
+}
+
+// writeSink 写入额外的输出目标，失败和恢复时更新进程状态
+func (lw *logWriter) writeSink(p []byte) {
+	_, err := lw.sink.Write(p)
+	if err == nil && !lw.sinkFailed {
+		return
+	}
+
+	lw.pm.mutex.Lock()
+	defer lw.pm.mutex.Unlock()
+
+	status, exists := lw.pm.processes[lw.name]
+	if !exists {
+		return
+	}
+
+	if err != nil {
+		// 只在首次失败时记录日志，避免每次写入都刷屏
+		if !lw.sinkFailed {
+			lw.pm.addLog(lw.name, fmt.Sprintf("WARNING: 输出写入失败: %v", err))
+			logWarnf("进程 %s 输出写入失败: %v", lw.name, err)
+		}
+		lw.sinkFailed = true
+		status.LogWriteError = err.Error()
+		return
+	}
+
+	lw.sinkFailed = false
+	status.LogWriteError = ""
+	lw.pm.addLog(lw.name, "INFO: 输出写入已恢复")
+}
+
+// Flush 输出缓冲中剩余的不完整行，在进程退出后调用
+func (lw *logWriter) Flush() {
+	if len(lw.partial) > 0 {
+		lw.writeLine(lw.partial)
+		lw.partial = nil
+	}
+}
+
+// writeLine 记录一行输出：先抹除敏感值再截断，避免截断位置落在敏感值中间时残留其前半部分
+func (lw *logWriter) writeLine(raw []byte) {
+	line := strings.TrimSpace(string(raw))
+	if line == "" {
+		return
+	}
+	line = lw.clip(scrubSecrets(line, lw.secrets))
+
+	// 添加时间戳和类型标识，由 logPump 批量写入，这里不获取任何锁
+	prefix := "STDOUT"
+	if !lw.isStdout {
+		prefix = "STDERR"
+	}
+	lw.pump.send(logEntry{
+		time:   time.Now(),
+		prefix: prefix,
+		line:   line,
+		keep:   lw.filter == nil || lw.filter.MatchString(line),
+	})
+}
+
+// needsSudo 检查是否需要 sudo 权限
+func needsSudo(command, user string) bool {
+	// 如果指定了用户，需要 sudo
+	if user != "" {
+		return true
+	}
+
+	// 检查文件权限或者根据路径判断
+	if strings.HasPrefix(command, "/opt/") || strings.HasPrefix(command, "/usr/") {
+		return true
+	}
+
+	// 检查文件所有者
+	if info, err := os.Stat(command); err == nil {
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			// 如果文件属于 root 用户
+			return stat.Uid == 0
+		}
+	}
+
+	return false
+}
+
+// GetProcesses 获取所有进程状态
+func (pm *ProcessManager) GetProcesses() map[string]*ProcessStatus {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	result := make(map[string]*ProcessStatus)
+	for k, v := range pm.processes {
+		result[k] = pm.copyStatus(v)
+	}
+	return result
+}
+
+// ListProcesses 获取按 order 和名称排序的进程状态列表
+func (pm *ProcessManager) ListProcesses() []*ProcessStatus {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	result := make([]*ProcessStatus, 0, len(pm.processes))
+	for _, v := range pm.processes {
+		result = append(result, pm.copyStatus(v))
+	}
+	sortProcesses(result)
+	return result
+}
+
+// sortProcesses 按 order 从小到大排序，order 相同时按名称排序
+func sortProcesses(processes []*ProcessStatus) {
+	slices.SortFunc(processes, func(a, b *ProcessStatus) int {
+		return cmp.Or(cmp.Compare(a.Config.Order, b.Config.Order), cmp.Compare(a.Config.Name, b.Config.Name))
+	})
+}
+
+// GetProcess 获取单个进程状态
+func (pm *ProcessManager) GetProcess(name string) (*ProcessStatus, bool) {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	status, exists := pm.processes[name]
+	if !exists {
+		return nil, false
+	}
+	return pm.copyStatus(status), true
+}
+
+// copyStatus 创建进程状态的深拷贝，切片和 map 也会复制，
+// 调用方释放锁后继续追加日志不会影响已返回的副本。调用方需持有锁
+func (pm *ProcessManager) copyStatus(status *ProcessStatus) *ProcessStatus {
+	// Output 由 logMutex 保护，复制整个结构体时也要持有
+	pm.logMutex.Lock()
+	statusCopy := *status
+	statusCopy.Output = slices.Clone(status.Output)
+	pm.logMutex.Unlock()
+
+	statusCopy.Config = copyProcessConfig(status.Config)
+	statusCopy.RestartHistory = slices.Clone(status.RestartHistory)
+	statusCopy.ExitCodeHistory = slices.Clone(status.ExitCodeHistory)
+	statusCopy.CommandLine = slices.Clone(status.CommandLine)
+	statusCopy.environment = slices.Clone(status.environment)
+	statusCopy.Silent = isSilent(&statusCopy, time.Now())
+	if status.counters != nil {
+		statusCopy.BytesStdin = status.counters.stdin.Load()
+		statusCopy.BytesStdout = status.counters.stdout.Load()
+		statusCopy.BytesStderr = status.counters.stderr.Load()
+	}
+	return &statusCopy
+}
+
+// isSilent 判断运行中的进程是否超过 silence_timeout 秒没有输出，本次启动后还没有输出时从启动时间算起
+func isSilent(status *ProcessStatus, now time.Time) bool {
+	if status.Status != "running" || status.Config.SilenceTimeout <= 0 {
+		return false
+	}
+	last := status.StartTime
+	if status.LastOutputTime.After(last) {
+		last = status.LastOutputTime
+	}
+	return now.Sub(last) > time.Duration(status.Config.SilenceTimeout)*time.Second
+}
+
+// copyProcessConfig 创建进程配置的深拷贝
+func copyProcessConfig(config ProcessConfig) ProcessConfig {
+	config.Args = slices.Clone(config.Args)
+	config.Environment = maps.Clone(config.Environment)
+	config.Tags = slices.Clone(config.Tags)
+	config.StopSequence = slices.Clone(config.StopSequence)
+	config.Watch = slices.Clone(config.Watch)
+	if config.AutoStart != nil {
+		autoStart := *config.AutoStart
+		config.AutoStart = &autoStart
+	}
+	return config
+}
+
+// startInitialProcesses 按配置顺序依次启动所有启用且尚未运行的进程，
+// 每两次启动之间等待 startup_stagger 秒，避免大量进程同时启动造成负载尖峰
+func (pm *ProcessManager) startInitialProcesses() {
+	pm.mutex.RLock()
+	var names []string
+	var stagger time.Duration
+	if pm.config != nil {
+		stagger = time.Duration(pm.config.Server.StartupStagger * float64(time.Second))
+		for _, processConfig := range pm.config.Processes {
+			// 接管的进程已在运行
+			status, exists := pm.processes[processConfig.Name]
+			if !exists || !status.Config.Enabled || status.Status == "running" {
+				continue
+			}
+			if !status.Config.autoStarts() {
+				logInfof("进程 %s 未启用 auto_start，等待手动启动", processConfig.Name)
+				continue
+			}
+			names = append(names, processConfig.Name)
+		}
+	}
+	pm.mutex.RUnlock()
+
+	for i, name := range names {
+		if i > 0 && stagger > 0 {
+			time.Sleep(stagger)
+		}
+		if err := pm.StartProcess(name); err != nil {
+			logErrorf("启动进程 %s 失败: %v", name, err)
+		}
+	}
+}
+
+// ReloadResult 最近一次加载配置（启动、手动重新加载或定期检查）的结果
+type ReloadResult struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error,omitempty"` // 为空表示成功；失败时正在运行的配置与磁盘上的文件不一致
+}
+
+// recordReload 记录加载配置的结果，失败后再次加载成功（或文件恢复为当前配置）时清除错误
+func (pm *ProcessManager) recordReload(err error) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	pm.lastReload = ReloadResult{Time: time.Now()}
+	if err != nil {
+		pm.lastReload.Error = err.Error()
+	}
+}
+
+// ReloadConfig 重新加载配置
+func (pm *ProcessManager) ReloadConfig() error {
+	logInfof("重新加载配置文件...")
+	return pm.LoadConfig()
+}
+
+//go:embed templates/index.html
+var templateFS embed.FS
+
+// indexTemplate 首页模板，启动时解析一次
+var indexTemplate = template.Must(template.ParseFS(templateFS, "templates/index.html"))
+
+// indexPageData 首页模板数据
+type indexPageData struct {
+	ConfigPath  string
+	RefreshTime int
+	Processes   []*ProcessStatus // 按 order 和名称排序
+	Tags        []string
+	Summary     ProcessSummary
+	Build       BuildInfo
+	ReadOnly    bool         // 只读模式，隐藏控制按钮
+	LastReload  ReloadResult // 最近一次加载配置失败时显示警告
+}
+
+// Web 处理器
+func (pm *ProcessManager) handleIndex(w http.ResponseWriter, r *http.Request) {
+	// "/" 会匹配所有未注册的路径，只有根路径渲染首页
+	if r.URL.Path != "/" {
+		handleNotFound(w, r)
+		return
+	}
+
+	refreshTime := 10
+	readOnly := false
+	pm.mutex.RLock()
+	lastReload := pm.lastReload
+	pm.mutex.RUnlock()
+	if pm.config != nil {
+		refreshTime = pm.config.Server.RefreshTime
+		readOnly = pm.config.Server.ReadOnly
+	}
+
+	processes := pm.GetProcesses()
+	var tags []string
+	for _, status := range processes {
+		for _, tag := range status.Config.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	slices.Sort(tags)
+
+	rows := slices.Collect(maps.Values(processes))
+	sortProcesses(rows)
+
+	data := indexPageData{
+		ConfigPath:  displayConfigPath(pm.configPath),
+		RefreshTime: refreshTime,
+		Processes:   rows,
+		Tags:        tags,
+		Summary:     summarizeProcesses(processes),
+		Build:       getBuildInfo(),
+		ReadOnly:    readOnly,
+		LastReload:  lastReload,
+	}
+	if err := indexTemplate.Execute(w, data); err != nil {
+		logErrorf("渲染页面失败: %v", err)
+	}
+}
+
+// notFoundPage 未知页面返回的 404 页面
+const notFoundPage = `<!DOCTYPE html>
+<html>
+<head>
+    <title>404 - LinkerBot Keeper</title>
+    <meta charset="UTF-8">
+</head>
+<body style="font-family: Arial, sans-serif; margin: 20px;">
+    <h1>404</h1>
+    <p>页面不存在，<a href="/">返回进程管理器</a></p>
+</body>
+</html>
+`
+
+// handleNotFound 未知路径返回 404，/api/ 下的路径返回 JSON
+func handleNotFound(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("接口不存在: %s", r.URL.Path),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusNotFound)
+	io.WriteString(w, notFoundPage)
+}
+
+// routes 注册 Web 界面和 API 的路由
+func (pm *ProcessManager) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /", pm.handleIndex)
+	mux.HandleFunc("GET /api/process/{name}/status", pm.handleProcessStatus)
+	mux.HandleFunc("GET /api/process/{name}/tail", pm.handleTail)
+	mux.HandleFunc("POST /api/process/{name}/{action}", pm.handleAPI)
+	mux.HandleFunc("POST /api/process/{name}/signal", pm.handleSignal)
+	mux.HandleFunc("POST /api/process/{name}/stdin", pm.handleStdin)
+	mux.HandleFunc("POST /api/all/{action}", pm.handleAll)
+	mux.HandleFunc("POST /api/group/{tag}/{action}", pm.handleGroup)
+	mux.HandleFunc("POST /api/enable/{name}", pm.handleEnable)
+	mux.HandleFunc("POST /api/reload", pm.handleReload)
+	mux.HandleFunc("POST /api/recover", pm.handleRecover)
+	mux.HandleFunc("GET /api/logs/search", pm.handleLogSearch)
+	mux.HandleFunc("GET /api/logs/{name}", pm.handleLogs)
+	mux.HandleFunc("GET /api/logs/{name}/download", pm.handleLogDownload)
+	mux.HandleFunc("DELETE /api/logs/{name}", pm.handleClearLogs)
+	mux.HandleFunc("GET /api/keeper/logs", pm.handleKeeperLogs)
+	mux.HandleFunc("GET /api/status", pm.handleStatus)
+	mux.HandleFunc("GET /api/processes", pm.handleProcessList)
+	mux.HandleFunc("GET /api/summary", pm.handleSummary)
+	mux.HandleFunc("GET /api/preflight", pm.handlePreflight)
+	mux.HandleFunc("GET /api/config", pm.handleConfig)
+	mux.HandleFunc("GET /api/version", pm.handleVersion)
+	mux.HandleFunc("GET /api/schema", pm.handleSchema)
+	mux.HandleFunc("GET /api/openapi.json", pm.handleOpenAPI)
+	mux.HandleFunc("GET /healthz", pm.handleHealthz)
+	mux.HandleFunc("GET /readyz", pm.handleReadyz)
+
+	return apiFallback(mux)
+}
+
+// apiMethods 探测 /api/ 路径支持哪些请求方法时尝试的方法
+var apiMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// apiFallback 处理 /api/ 下没有匹配路由的请求，按 API 的格式返回 JSON 错误：
+// 路径存在但方法不支持时返回 405 和 Allow，路径不存在时返回 404，而不是 ServeMux 的纯文本响应。
+// "GET /" 匹配所有路径，不算作 API 路由
+func apiFallback(mux *http.ServeMux) http.Handler {
+	routed := func(r *http.Request) bool {
+		_, pattern := mux.Handler(r)
+		return pattern != "" && pattern != "GET /"
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || routed(r) {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		var allowed []string
+		for _, method := range apiMethods {
+			probe := r.Clone(r.Context())
+			probe.Method = method
+			if routed(probe) {
+				allowed = append(allowed, method)
+			}
+		}
+		if len(allowed) == 0 {
+			handleNotFound(w, r)
+			return
+		}
+		methodNotAllowed(strings.Join(allowed, ", "))(w, r)
+	})
+}
+
+// methodNotAllowed 返回拒绝请求方法的处理器
+func methodNotAllowed(allowed string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allowed)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("不支持的请求方法 %s，请使用 %s", r.Method, allowed),
+		})
+	}
+}
+
+// httpStatusFromError 根据错误类别返回对应的 HTTP 状态码
+func httpStatusFromError(err error) int {
+	switch {
+	case errors.Is(err, ErrProcessNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrUnknownAction), errors.Is(err, ErrInvalidSignal):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrProcessRunning), errors.Is(err, ErrProcessNotRunning), errors.Is(err, ErrProcessDisabled), errors.Is(err, ErrStdinClosed), errors.Is(err, ErrProcessLocked):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// API 处理器
+func (pm *ProcessManager) handleAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// 路径参数：/api/process/{name}/{action}
+	name := r.PathValue("name")
+	action := r.PathValue("action")
+
+	var message string
+	var err error
+	if action == "start" && r.URL.Query().Get("force") == "true" {
+		err = pm.ForceStartProcess(name)
+		message = fmt.Sprintf("进程 %s 已强制启动", name)
+	} else {
+		message, err = pm.processAction(name, action)
+	}
+
+	if err != nil {
+		response := map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+		// 启动失败时附带最近的日志（包括 pre_start 输出），无需再打开日志窗口查看原因
+		if startFailed(action, err) {
+			response["logs"] = pm.recentLogs(name, startFailureLogLines)
+		}
+		w.WriteHeader(httpStatusFromError(err))
+		json.NewEncoder(w).Encode(response)
+	} else {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": message,
+		})
+	}
+}
+
+// startFailureLogLines 启动失败时在响应中附带的日志行数
+const startFailureLogLines = 10
+
+// startFailed 判断操作错误是否为进程启动失败（而不是进程不存在、已在运行等请求本身的问题）
+func startFailed(action string, err error) bool {
+	if action != "start" && action != "restart" && action != "recover" {
+		return false
+	}
+	return !errors.Is(err, ErrProcessNotFound) && !errors.Is(err, ErrProcessRunning) && !errors.Is(err, ErrUnknownAction)
+}
+
+// recentLogs 返回进程日志缓冲的最后 n 行，进程不存在时返回空
+func (pm *ProcessManager) recentLogs(name string, n int) []string {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	status, exists := pm.processes[name]
+	if !exists {
+		return nil
 	}
-
-	return len(p), nil
+	lines := pm.outputLines(status)
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
 }
 
-// needsSudo 检查是否需要 sudo 权限
-func needsSudo(command, user string) bool {
-	// 如果指定了用户，需要 sudo
-	if user != "" {
-		return true
-	}
+// 单个进程状态 API
+func (pm *ProcessManager) handleProcessStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-	// 检查文件权限或者根据路径判断
-	if strings.HasPrefix(command, "/opt/") || strings.HasPrefix(command, "/usr/") {
-		return true
+	name := r.PathValue("name")
+	status, exists := pm.GetProcess(name)
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("进程 %s 不存在", name),
+		})
+		return
 	}
 
-	// 检查文件所有者
-	if info, err := os.Stat(command); err == nil {
-		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
-			// 如果文件属于 root 用户
-			return stat.Uid == 0
-		}
+	secretKeys := pm.secretKeys()
+	status.Config = redactProcessConfig(status.Config, secretKeys)
+
+	// ?env=true 时附带启动时的环境变量，敏感值已脱敏
+	if r.URL.Query().Get("env") == "true" {
+		json.NewEncoder(w).Encode(struct {
+			*ProcessStatus
+			Environment map[string]string `json:"environment"`
+		}{status, redactEnvironment(status.environment, secretKeys)})
+		return
 	}
 
-	return false
+	json.NewEncoder(w).Encode(status)
 }
 
-// GetProcesses 获取所有进程状态
-func (pm *ProcessManager) GetProcesses() map[string]*ProcessStatus {
+// secretKeys 获取敏感环境变量关键字，未配置时使用默认值
+func (pm *ProcessManager) secretKeys() []string {
 	pm.mutex.RLock()
 	defer pm.mutex.RUnlock()
 
-	result := make(map[string]*ProcessStatus)
-	for k, v := range pm.processes {
-		// 创建副本避免并发问题
-		statusCopy := *v
-		result[k] = &statusCopy
+	if pm.config == nil {
+		return defaultSecretKeys
 	}
-	return result
-}
-
-// ReloadConfig 重新加载配置
-func (pm *ProcessManager) ReloadConfig() error {
-	log.Println("重新加载配置文件...")
-	return pm.LoadConfig()
+	return effectiveSecretKeys(pm.config.Server)
 }
 
-// Web 处理器
-func (pm *ProcessManager) handleIndex(w http.ResponseWriter, r *http.Request) {
-	refreshTime := 10
-	if pm.config != nil {
-		refreshTime = pm.config.Server.RefreshTime
-	}
-
-	tmpl := fmt.Sprintf(`
-<!DOCTYPE html>
-<html>
-<head>
-    <title>LinkerBot Keeper</title>
-    <meta charset="UTF-8">
-    <meta http-equiv="refresh" content="%d">
-    <style>
-        body { font-family: Arial, sans-serif; margin: 20px; }
-        table { width: 100%%; border-collapse: collapse; margin-top: 20px; }
-        th, td { border: 1px solid #ddd; padding: 12px; text-align: left; }
-        th { background-color: #f2f2f2; }
-        .status-running { color: green; font-weight: bold; }
-        .status-stopped { color: red; font-weight: bold; }
-        .status-error { color: orange; font-weight: bold; }
-        .status-disabled { color: gray; font-weight: bold; }
-        button { padding: 8px 16px; margin: 2px; cursor: pointer; border: none; border-radius: 3px; }
-        .btn-start { background-color: #4CAF50; color: white; }
-        .btn-stop { background-color: #f44336; color: white; }
-        .btn-restart { background-color: #2196F3; color: white; }
-        .btn-enable { background-color: #FF9800; color: white; }
-        .btn-logs { background-color: #9C27B0; color: white; }
-        .btn-reload { background-color: #607D8B; color: white; }
-        .refresh-btn { background-color: #FF9800; color: white; padding: 10px 20px; margin-bottom: 20px; }
-        .info-box { background-color: #e7f3ff; border: 1px solid #b3d9ff; padding: 10px; margin-bottom: 20px; border-radius: 5px; }
-        .config-info { background-color: #f0f8ff; border: 1px solid #b0d4f0; padding: 10px; margin-bottom: 20px; border-radius: 5px; }
-        .loading { opacity: 0.6; pointer-events: none; }
-        .description { font-size: 12px; color: #666; }
-    </style>
-</head>
-<body>
-    <h1>进程管理器</h1>
-    
-    <div class="config-info">
-        <strong>配置信息：</strong>
-        <br>配置文件: %s
-        <br>页面刷新间隔: %d秒
-        <br><button class="btn-reload" onclick="reloadConfig()">重新加载配置</button>
-    </div>
-    
-    <div class="info-box">
-        <strong>说明：</strong>
-        <ul>
-            <li>页面每%d秒自动刷新</li>
-            <li>进程重启超过配置的最大次数会自动禁用</li>
-            <li>可以通过"启用重启"按钮重新启用并重置计数</li>
-            <li>点击"日志"查看进程详细输出</li>
-            <li>支持JSON和YAML配置文件格式</li>
-        </ul>
-    </div>
-    
-    <button class="refresh-btn" onclick="location.reload()">手动刷新</button>
-    
-    <table>
-        <tr>
-            <th>进程名称</th>
-            <th>描述</th>
-            <th>状态</th>
-            <th>PID</th>
-            <th>启动时间</th>
-            <th>重启次数</th>
-            <th>退出码</th>
-            <th>最后错误</th>
-            <th>操作</th>
-        </tr>
-        {{range $name, $status := .}}
-        <tr>
-            <td>
-                <strong>{{$name}}</strong>
-                <br><small>{{$status.Config.Command}}</small>
-            </td>
-            <td class="description">{{$status.Config.Description}}</td>
-            <td class="status-{{$status.Status}}">{{$status.Status}}</td>
-            <td>{{if ne $status.PID 0}}{{$status.PID}}{{else}}-{{end}}</td>
-            <td>{{if not $status.StartTime.IsZero}}{{$status.StartTime.Format "2006-01-02 15:04:05"}}{{else}}-{{end}}</td>
-            <td>{{$status.Restarts}}/{{$status.Config.MaxRestarts}}</td>
-            <td>{{if ne $status.LastExitCode 0}}{{$status.LastExitCode}}{{else}}-{{end}}</td>
-            <td title="{{$status.LastError}}">{{if $status.LastError}}{{printf "%%.30s" $status.LastError}}{{if gt (len $status.LastError) 30}}...{{end}}{{else}}-{{end}}</td>
-            <td>
-                {{if eq $status.Status "disabled"}}
-                    <button class="btn-enable" onclick="controlProcess('{{$name}}', 'enable')">启用重启</button>
-                {{else}}
-                    <button class="btn-start" onclick="controlProcess('{{$name}}', 'start')" {{if eq $status.Status "running"}}disabled{{end}}>启动</button>
-                    <button class="btn-stop" onclick="controlProcess('{{$name}}', 'stop')" {{if ne $status.Status "running"}}disabled{{end}}>停止</button>
-                    <button class="btn-restart" onclick="controlProcess('{{$name}}', 'restart')">重启</button>
-                {{end}}
-                <button class="btn-logs" onclick="showLogs('{{$name}}')">日志</button>
-            </td>
-        </tr>
-        {{end}}
-    </table>
-
-    <!-- 日志模态框 -->
-    <div id="logModal" style="display:none; position:fixed; top:0; left:0; width:100%%; height:100%%; background-color:rgba(0,0,0,0.7); z-index:1000;">
-        <div style="position:relative; margin:2%% auto; width:90%%; background-color:white; padding:20px; border-radius:5px; max-height:90%%; overflow-y:auto;">
-            <h3 id="logTitle">进程日志</h3>
-            <button onclick="closeLogModal()" style="float:right; margin-top:-40px; padding:5px 10px;">关闭</button>
-            <pre id="logContent" style="background-color:#f5f5f5; padding:15px; border-radius:3px; max-height:500px; overflow-y:auto; font-size:12px; line-height:1.4;"></pre>
-        </div>
-    </div>
-
-    <script>
-        function controlProcess(name, action) {
-            // 添加加载状态
-            const buttons = document.querySelectorAll('button');
-            buttons.forEach(btn => btn.classList.add('loading'));
-            
-            let url = '/api/process/' + name + '/' + action;
-            if (action === 'enable') {
-                url = '/api/enable/' + name;
-            }
-            
-            fetch(url, {
-                method: 'POST'
-            })
-            .then(response => response.json())
-            .then(data => {
-                if (data.success) {
-                    alert('操作成功: ' + data.message);
-                    setTimeout(() => location.reload(), 1000);
-                } else {
-                    alert('操作失败: ' + data.error);
-                    buttons.forEach(btn => btn.classList.remove('loading'));
-                }
-            })
-            .catch(error => {
-                alert('请求失败: ' + error);
-                buttons.forEach(btn => btn.classList.remove('loading'));
-            });
-        }
-
-        function reloadConfig() {
-            const buttons = document.querySelectorAll('button');
-            buttons.forEach(btn => btn.classList.add('loading'));
-            
-            fetch('/api/reload', {
-                method: 'POST'
-            })
-            .then(response => response.json())
-            .then(data => {
-                if (data.success) {
-                    alert('配置重新加载成功: ' + data.message);
-                    setTimeout(() => location.reload(), 1000);
-                } else {
-                    alert('配置重新加载失败: ' + data.error);
-                    buttons.forEach(btn => btn.classList.remove('loading'));
-                }
-            })
-            .catch(error => {
-                alert('请求失败: ' + error);
-                buttons.forEach(btn => btn.classList.remove('loading'));
-            });
-        }
-
-        function showLogs(name) {
-            fetch('/api/logs/' + name)
-            .then(response => response.json())
-            .then(data => {
-                document.getElementById('logTitle').textContent = '进程 ' + name + ' 的日志';
-                const logs = data.logs || [];
-                if (logs.length === 0) {
-                    document.getElementById('logContent').textContent = '暂无日志记录';
-                } else {
-                    document.getElementById('logContent').textContent = logs.join('\\n');
-                }
-                document.getElementById('logModal').style.display = 'block';
-            })
-            .catch(error => {
-                alert('获取日志失败: ' + error);
-            });
-        }
-
-        function closeLogModal() {
-            document.getElementById('logModal').style.display = 'none';
-        }
-
-        // 点击模态框外部关闭
-        window.onclick = function(event) {
-            const modal = document.getElementById('logModal');
-            if (event.target === modal) {
-                modal.style.display = 'none';
-            }
-        }
-    </script>
-</body>
-</html>`, refreshTime, pm.configPath, refreshTime, refreshTime)
+// 批量操作 API
+func (pm *ProcessManager) handleAll(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-	t := template.Must(template.New("index").Parse(tmpl))
-	processes := pm.GetProcesses()
-	t.Execute(w, processes)
+	results, err := pm.ApplyToAll(r.PathValue("action"))
+	writeBulkResults(w, results, err)
 }
 
-// API 处理器
-func (pm *ProcessManager) handleAPI(w http.ResponseWriter, r *http.Request) {
+// 分组批量操作 API
+func (pm *ProcessManager) handleGroup(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// 解析路径：/api/process/name/action
-	path := r.URL.Path[len("/api/process/"):]
-	parts := strings.Split(path, "/")
-	if len(parts) < 2 {
+	results, err := pm.ApplyToGroup(r.PathValue("tag"), r.PathValue("action"))
+	writeBulkResults(w, results, err)
+}
+
+// writeBulkResults 输出批量操作结果，任一进程失败时 success 为 false
+func writeBulkResults(w http.ResponseWriter, results map[string]BulkResult, err error) {
+	if err != nil {
+		w.WriteHeader(httpStatusFromError(err))
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
-			"error":   "无效的 API 路径",
+			"error":   err.Error(),
 		})
 		return
 	}
 
-	name := parts[0]
-	action := parts[1]
+	success := true
+	for _, result := range results {
+		if !result.Success {
+			success = false
+			break
+		}
+	}
 
-	var err error
-	var message string
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": success,
+		"results": results,
+	})
+}
 
-	switch action {
-	case "start":
-		err = pm.StartProcess(name)
-		message = fmt.Sprintf("进程 %s 启动成功", name)
-	case "stop":
-		err = pm.StopProcess(name)
-		message = fmt.Sprintf("进程 %s 停止成功", name)
-	case "restart":
-		err = pm.RestartProcess(name)
-		message = fmt.Sprintf("进程 %s 重启成功", name)
-	default:
-		err = fmt.Errorf("未知操作: %s", action)
+// 恢复出错进程 API
+func (pm *ProcessManager) handleRecover(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	writeBulkResults(w, pm.RecoverProcesses(), nil)
+}
+
+// 发送信号 API，信号通过 ?signal= 或 JSON 请求体 {"signal": "HUP"} 指定，
+// 默认发送给整个进程组，?group=false 时只发送给主进程
+func (pm *ProcessManager) handleSignal(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	name := r.PathValue("name")
+	value := r.URL.Query().Get("signal")
+	if value == "" && r.Body != nil {
+		var body struct {
+			Signal string `json:"signal"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err == nil {
+			value = body.Signal
+		}
 	}
 
+	sig, err := parseSignal(value)
+	if err == nil {
+		err = pm.SignalProcess(name, sig, r.URL.Query().Get("group") != "false")
+	}
 	if err != nil {
+		w.WriteHeader(httpStatusFromError(err))
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
 			"error":   err.Error(),
 		})
-	} else {
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": true,
-			"message": message,
-		})
+		return
 	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("已向进程 %s 发送信号 %s", name, signalName(sig)),
+	})
 }
 
 // 启用自动重启 API
 func (pm *ProcessManager) handleEnable(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	name := r.URL.Path[len("/api/enable/"):]
+	name := r.PathValue("name")
 
 	err := pm.EnableAutoRestart(name)
 	if err != nil {
+		w.WriteHeader(httpStatusFromError(err))
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
 			"error":   err.Error(),
@@ -948,6 +3014,7 @@ func (pm *ProcessManager) handleReload(w http.ResponseWriter, r *http.Request) {
 
 	err := pm.ReloadConfig()
 	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
 			"error":   err.Error(),
@@ -964,130 +3031,471 @@ func (pm *ProcessManager) handleReload(w http.ResponseWriter, r *http.Request) {
 func (pm *ProcessManager) handleLogs(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	name := r.URL.Path[len("/api/logs/"):]
+	name := r.PathValue("name")
 
 	pm.mutex.RLock()
 	defer pm.mutex.RUnlock()
 
 	if status, exists := pm.processes[name]; exists {
+		// 标记匹配 log_highlight 的行，供界面标红显示
+		lines := pm.outputLines(status)
+		highlight := make([]bool, len(lines))
+		if pattern := compilePattern(status.Config.LogHighlight); pattern != nil {
+			for i, line := range lines {
+				highlight[i] = pattern.MatchString(line)
+			}
+		}
+
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": true,
-			"logs":    status.Output,
+			"success":      true,
+			"logs":         lines,
+			"highlight":    highlight,
+			"command_line": status.CommandLine,
+		})
+	} else {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "进程不存在",
+		})
+	}
+}
+
+// 清空日志 API
+func (pm *ProcessManager) handleClearLogs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	name := r.PathValue("name")
+
+	err := pm.ClearLogs(name)
+	if err != nil {
+		w.WriteHeader(httpStatusFromError(err))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
 		})
 	} else {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": fmt.Sprintf("进程 %s 的日志已清空", name),
+		})
+	}
+}
+
+// 日志下载 API，以纯文本附件形式返回日志缓冲，客户端支持时以 gzip 压缩传输
+func (pm *ProcessManager) handleLogDownload(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	pm.mutex.RLock()
+	status, exists := pm.processes[name]
+	var lines []string
+	if exists {
+		lines = pm.outputLines(status)
+	}
+	pm.mutex.RUnlock()
+
+	if !exists {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
 			"error":   "进程不存在",
 		})
+		return
+	}
+
+	filename := fmt.Sprintf("%s-%s.log", unsafeFilenameChars.ReplaceAllString(name, "_"), time.Now().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	// 客户端支持 gzip 时边压缩边写出，不在内存中生成完整的压缩结果
+	var out io.Writer = w
+	if acceptsGzip(r) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+	}
+	buffered := bufio.NewWriter(out)
+	defer buffered.Flush()
+	for _, line := range lines {
+		fmt.Fprintln(buffered, line)
+	}
+}
+
+// acceptsGzip 判断请求的 Accept-Encoding 是否接受 gzip（q=0 表示不接受）
+func acceptsGzip(r *http.Request) bool {
+	for _, value := range r.Header.Values("Accept-Encoding") {
+		for _, part := range strings.Split(value, ",") {
+			coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding != "gzip" && coding != "*" {
+				continue
+			}
+			if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+				if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+					continue
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// unsafeFilenameChars 下载文件名中需要替换的字符
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// keeper 自身日志 API，?lines=N 只返回最近 N 行
+func (pm *ProcessManager) handleKeeperLogs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	lines, err := parseNonNegativeInt(r.URL.Query().Get("lines"), 0)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("无效的 lines 参数: %v", err),
+		})
+		return
 	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"logs":    keeperLogs.Lines(lines),
+	})
 }
 
 // 状态 API
 func (pm *ProcessManager) handleStatus(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	processes := pm.GetProcesses()
+	// 返回按 order 和名称排序的数组，便于客户端比较前后两次的响应
+	processes := pm.ListProcesses()
+	secretKeys := pm.secretKeys()
+	for _, status := range processes {
+		status.Config = redactProcessConfig(status.Config, secretKeys)
+	}
 	json.NewEncoder(w).Encode(processes)
 }
 
+// 进程列表 API，支持 ?status=、?tag= 过滤和 ?offset=&limit= 分页
+func (pm *ProcessManager) handleProcessList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	offset, err := parseNonNegativeInt(query.Get("offset"), 0)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("无效的 offset 参数: %v", err),
+		})
+		return
+	}
+	limit, err := parseNonNegativeInt(query.Get("limit"), 0)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("无效的 limit 参数: %v", err),
+		})
+		return
+	}
+
+	statusFilter := query.Get("status")
+	tagFilter := query.Get("tag")
+	secretKeys := pm.secretKeys()
+	processes := make([]*ProcessStatus, 0)
+	for _, status := range pm.ListProcesses() {
+		status.Config = redactProcessConfig(status.Config, secretKeys)
+		if (statusFilter == "" || status.Status == statusFilter) &&
+			(tagFilter == "" || slices.Contains(status.Config.Tags, tagFilter)) {
+			processes = append(processes, status)
+		}
+	}
+
+	total := len(processes)
+	if offset > total {
+		offset = total
+	}
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"total":     total,
+		"offset":    offset,
+		"limit":     limit,
+		"processes": processes[offset:end],
+	})
+}
+
+// parseNonNegativeInt 解析非负整数查询参数，为空时返回默认值
+func parseNonNegativeInt(value string, defaultValue int) (int, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("不能为负数: %d", n)
+	}
+	return n, nil
+}
+
+// 存活检查，HTTP 服务可用且配置已加载时返回 200
+func (pm *ProcessManager) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	pm.mutex.RLock()
+	loaded := pm.config != nil
+	pm.mutex.RUnlock()
+
+	if !loaded {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "error", "error": "配置未加载"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok"})
+}
+
+// 就绪检查，初始配置加载成功且启用的进程已完成首次启动时返回 200
+func (pm *ProcessManager) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if !pm.ready.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "starting", "error": "正在启动进程"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok"})
+}
+
+// 版本 API
+func (pm *ProcessManager) handleVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(getBuildInfo())
+}
+
 // 配置 API
 func (pm *ProcessManager) handleConfig(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	pm.mutex.RLock()
 	config := pm.config
+	lastReload := pm.lastReload
 	pm.mutex.RUnlock()
 
 	if config == nil {
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "配置未加载",
+			"success":     false,
+			"error":       "配置未加载",
+			"last_reload": lastReload,
 		})
 		return
 	}
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"config":  config,
+		"success":     true,
+		"config":      redactConfig(config, pm.secretKeys()),
+		"last_reload": lastReload,
 	})
 }
 
 func main() {
+	// 第一个参数是子命令时按子命令执行，否则与 serve 相同，兼容旧的用法
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "serve":
+			serve(args[1:])
+			return
+		case "status":
+			os.Exit(runStatus(args[1:]))
+		case "validate":
+			os.Exit(runValidate(args[1:]))
+		}
+	}
+	serve(args)
+}
+
+// serve 加载配置、启动进程并提供 Web 界面，直到收到退出信号
+func serve(args []string) {
 	// 解析命令行参数
-	configPath := "keeper.yaml"
-	if len(os.Args) > 1 {
-		configPath = os.Args[1]
+	var overrides ServerOverrides
+	configPath := flag.String("config", "keeper.yaml", "配置文件路径（支持 .json, .yaml, .yml），- 表示从标准输入读取，也可以是 http(s):// 地址")
+	configFormat := flag.String("config-format", "", "标准输入或 URL 配置的格式：json, yaml，默认根据 Content-Type、扩展名或内容推断")
+	profile := flag.String("profile", "", "使用的配置 profile（配置文件中 profiles 下的名称），默认读取环境变量 "+profileEnvVar)
+	flag.StringVar(&overrides.Host, "host", "", "Web 服务监听地址，覆盖配置文件中的 server.host")
+	flag.StringVar(&overrides.Port, "port", "", "Web 服务端口，覆盖配置文件中的 server.port")
+	flag.StringVar(&overrides.LogLevel, "log-level", "", "日志级别：debug, info, warn, error，覆盖配置文件中的 server.log_level")
+	flag.Usage = func() {
+		program := filepath.Base(os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "用法: %s [serve] [选项] [配置文件]\n", program)
+		fmt.Fprintf(flag.CommandLine.Output(), "      %s status [选项] [配置文件]    查看运行中 keeper 的进程状态\n", program)
+		fmt.Fprintf(flag.CommandLine.Output(), "      %s validate [选项] [配置文件]  检查配置文件\n\n选项:\n", program)
+		flag.PrintDefaults()
+	}
+	flag.CommandLine.Parse(args)
+
+	// 兼容旧的位置参数形式: keeper /path/to/config.yaml
+	path, err := configPathArg(flag.CommandLine, *configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if overrides.LogLevel != "" {
+		if _, err := parseLogLevel(overrides.LogLevel); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			flag.Usage()
+			os.Exit(2)
+		}
+	}
+
+	format, err := parseConfigFormat(*configFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
 	}
 
-	pm := NewProcessManager(configPath)
+	pm := NewProcessManager(path, overrides)
+	pm.configFormat = format
+	pm.profile = *profile
+	if pm.profile == "" {
+		pm.profile = os.Getenv(profileEnvVar)
+	}
+	if pm.profile != "" {
+		logInfof("使用配置 profile: %s", pm.profile)
+	}
 
 	// 加载配置
-	err := pm.LoadConfig()
+	err = pm.LoadConfig()
 	if err != nil {
 		log.Fatalf("加载配置失败: %v", err)
 	}
 
-	// 检查可执行文件是否存在
-	log.Println("检查可执行文件...")
+	// 接管上次分离退出时仍在运行的进程，再按 on_orphan 处理 keeper 异常退出后遗留的进程
+	pm.AdoptProcesses()
+	pm.ReconcileOrphans()
+
+	// 输出加载配置时的启动前检查结果，详细结果可通过 /api/preflight 查询
+	logInfof("检查可执行文件...")
 	for name, status := range pm.GetProcesses() {
-		execPath := status.Config.Command
-		if filepath.IsAbs(execPath) {
-			if _, err := os.Stat(execPath); os.IsNotExist(err) {
-				log.Printf("警告: 可执行文件 %s 不存在，进程 %s 将无法启动", execPath, name)
+		if result := status.Preflight; result != nil {
+			if result.OK() {
+				logInfof("发现命令: %s", result.Executable)
 			} else {
-				log.Printf("发现可执行文件: %s", execPath)
-			}
-		} else {
-			if _, err := exec.LookPath(execPath); err != nil {
-				log.Printf("警告: 命令 %s 在PATH中不存在，进程 %s 将无法启动", execPath, name)
-			} else {
-				log.Printf("发现命令: %s", execPath)
+				logWarnf("警告: 进程 %s 将无法启动: %s", name, result.Note)
 			}
 		}
 	}
 
 	// 启动所有启用的进程
-	for name, status := range pm.GetProcesses() {
-		if status.Config.Enabled {
-			go func(processName string) {
-				time.Sleep(2 * time.Second) // 延迟启动
-				err := pm.StartProcess(processName)
-				if err != nil {
-					log.Printf("启动进程 %s 失败: %v", processName, err)
-				}
-			}(name)
-		}
-	}
+	go func() {
+		time.Sleep(2 * time.Second) // 延迟启动
+		pm.startInitialProcesses()
+		pm.ready.Store(true)
+	}()
 
-	// 定期检查配置文件变化
+	// 定期检查配置文件变化，从标准输入读取的配置不会变化
 	go func() {
+		if isStdinConfig(pm.configPath) {
+			return
+		}
+
 		ticker := time.NewTicker(30 * time.Second)
 		defer ticker.Stop()
 
 		for range ticker.C {
 			err := pm.LoadConfig()
 			if err != nil {
-				log.Printf("定期加载配置失败: %v", err)
+				logErrorf("定期加载配置失败，继续使用当前配置: %v", err)
 			}
 		}
 	}()
 
-	// 设置 Web 路由
-	http.HandleFunc("/", pm.handleIndex)
-	http.HandleFunc("/api/process/", pm.handleAPI)
-	http.HandleFunc("/api/enable/", pm.handleEnable)
-	http.HandleFunc("/api/reload", pm.handleReload)
-	http.HandleFunc("/api/logs/", pm.handleLogs)
-	http.HandleFunc("/api/status", pm.handleStatus)
-	http.HandleFunc("/api/config", pm.handleConfig)
-
-	// 启动 Web 服务器
-	address := "0.0.0.0:8080"
-	if pm.config != nil {
-		address = fmt.Sprintf("%s:%s", pm.config.Server.Host, pm.config.Server.Port)
+	// 启动 Web 服务器，每个监听地址使用同一组路由，单个地址停止服务只记录错误
+	web := newWebServer(accessLog(pm.readOnlyGuard(pm.rateLimit(pm.routes()))))
+	logInfof("进程管理器（%s）启动", Version)
+	logInfof("配置文件: %s", displayConfigPath(pm.configPath))
+	if err := web.start(pm.config.Server); err != nil {
+		log.Fatalf("监听失败: %v", err)
+	}
+
+	// 之后重新加载配置时，监听地址的变化会重新绑定
+	pm.mutex.Lock()
+	pm.web = web
+	pm.mutex.Unlock()
+
+	// 退出时按配置分离或停止子进程，并删除 Unix 套接字文件
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		sig := <-signals
+		logInfof("收到信号 %v，退出", sig)
+		pm.Shutdown()
+		web.removeSockets()
+		os.Exit(0)
+	}()
+
+	log.Fatal(web.wait())
+}
+
+// listenAddresses 返回要监听的 TCP 地址。配置了 listen 时逐个解析，否则使用 host 和 port；
+// 没有端口的地址使用 port，IPv6 地址可以写成 [::1]:8080、[::1] 或 ::1
+func listenAddresses(server ServerConfig) ([]string, error) {
+	if len(server.Listen) == 0 {
+		return []string{net.JoinHostPort(strings.Trim(server.Host, "[]"), server.Port)}, nil
+	}
+
+	addresses := make([]string, 0, len(server.Listen))
+	seen := make(map[string]bool, len(server.Listen))
+	for _, entry := range server.Listen {
+		host, port, err := splitListenAddress(strings.TrimSpace(entry), server.Port)
+		if err != nil {
+			return nil, fmt.Errorf("监听地址 %q 无效: %v", entry, err)
+		}
+		address := net.JoinHostPort(host, port)
+		if seen[address] {
+			return nil, fmt.Errorf("监听地址 %q 重复", entry)
+		}
+		seen[address] = true
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// splitListenAddress 拆分监听地址的主机和端口，没有端口时使用 defaultPort
+func splitListenAddress(entry, defaultPort string) (string, string, error) {
+	if entry == "" {
+		return "", "", fmt.Errorf("地址为空")
 	}
 
-	log.Printf("进程管理器（%s）启动", Version)
-	log.Printf("配置文件: %s", configPath)
-	log.Printf("Web界面: http://%s", address)
-	log.Fatal(http.ListenAndServe(address, nil))
+	host, port, err := net.SplitHostPort(entry)
+	if err != nil {
+		// 没有端口：[::1]、::1 或普通主机名
+		host = strings.TrimSuffix(strings.TrimPrefix(entry, "["), "]")
+		if strings.Contains(host, ":") && net.ParseIP(host) == nil {
+			return "", "", err
+		}
+		port = defaultPort
+	}
+	if port == "" {
+		return "", "", fmt.Errorf("缺少端口")
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return "", "", fmt.Errorf("端口无效: %s", port)
+	}
+	if strings.Contains(host, ":") && net.ParseIP(host) == nil {
+		return "", "", fmt.Errorf("IPv6 地址无效: %s", host)
+	}
+	return host, port, nil
 }
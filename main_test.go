@@ -0,0 +1,488 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+func TestLoadConfigKeepsStateOnBrokenConfig(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: svc
+    command: fake-sleep
+    enabled: true
+`)
+	if err := pm.StartProcess("svc"); err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+
+	pm.mutex.RLock()
+	config, fingerprint := pm.config, pm.fingerprint
+	status := pm.processes["svc"]
+	pm.mutex.RUnlock()
+	pid := processState(t, pm, "svc").PID
+
+	broken := map[string]string{
+		"无法解析的 YAML": "processes:\n  - name: svc\n    command: [fake-sleep\n",
+		"校验失败":       "processes:\n  - name: svc\n    command: fake-sleep\n  - name: svc\n    command: fake-sleep\n",
+	}
+	for what, content := range broken {
+		writeTestConfig(t, pm.configPath, content)
+		if err := pm.LoadConfig(); err == nil {
+			t.Fatalf("%s的配置加载成功", what)
+		}
+
+		pm.mutex.RLock()
+		if pm.config != config || pm.fingerprint != fingerprint {
+			t.Errorf("%s后 config 或 fingerprint 被替换", what)
+		}
+		if len(pm.processes) != 1 || pm.processes["svc"] != status {
+			t.Errorf("%s后进程状态被替换: %v", what, pm.processes)
+		}
+		if pm.lastReload.Error == "" {
+			t.Errorf("%s后 lastReload.Error 为空", what)
+		}
+		pm.mutex.RUnlock()
+
+		if current := processState(t, pm, "svc"); current.Status != "running" || current.PID != pid {
+			t.Errorf("%s后进程状态为 %s，PID %d（原 PID %d）", what, current.Status, current.PID, pid)
+		}
+	}
+}
+
+// 用 go test -race 运行：并发读取状态副本、追加日志和启动进程时不应出现数据竞争，
+// 修改返回的副本也不影响进程管理器中的状态
+func TestGetProcessesConcurrentWithLogs(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: chatty
+    command: fake-echo
+    args: ["hello"]
+    enabled: true
+    environment:
+      MODE: test
+`)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	run := func(f func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+					f(i)
+				}
+			}
+		}()
+	}
+
+	run(func(i int) {
+		pm.mutex.Lock()
+		pm.addLog("chatty", fmt.Sprintf("INFO: 第 %d 行", i))
+		pm.mutex.Unlock()
+	})
+	run(func(int) {
+		// fake-echo 很快退出，反复启动让输出协程持续写入日志缓冲
+		pm.StartProcess("chatty")
+	})
+	for range 2 {
+		run(func(int) {
+			for _, status := range pm.GetProcesses() {
+				for _, line := range status.Output {
+					_ = len(line)
+				}
+				status.Output = append(status.Output, "副本中的行")
+				status.Config.Args[0] = "changed"
+				status.Config.Environment["MODE"] = "changed"
+			}
+		})
+	}
+
+	waitFor(t, "进程输出写入日志缓冲", func() bool {
+		return slices.ContainsFunc(processState(t, pm, "chatty").Output, func(line string) bool {
+			return strings.HasSuffix(line, "STDOUT: hello")
+		})
+	})
+	close(stop)
+	wg.Wait()
+
+	status := processState(t, pm, "chatty")
+	if status.Config.Args[0] != "hello" || status.Config.Environment["MODE"] != "test" {
+		t.Fatalf("修改副本影响了进程配置: %v %v", status.Config.Args, status.Config.Environment)
+	}
+	for _, line := range status.Output {
+		if line == "副本中的行" {
+			t.Fatal("追加到副本的日志出现在日志缓冲中")
+		}
+	}
+}
+
+func TestConcurrentStartSpawnsOnce(t *testing.T) {
+	// pre_start 执行期间会释放锁，并发的启动请求正好落在这个窗口内
+	pm, runner := newTestManager(t, `
+processes:
+  - name: svc
+    command: fake-sleep
+    enabled: true
+    pre_start: sleep 0.2
+`)
+
+	const starters = 20
+	errs := make(chan error, starters)
+	var wg sync.WaitGroup
+	for range starters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- pm.StartProcess("svc")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	started := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			started++
+		case !errors.Is(err, ErrProcessRunning):
+			t.Errorf("StartProcess 返回意外的错误: %v", err)
+		}
+	}
+	if started != 1 {
+		t.Fatalf("%d 次启动成功，期望 1 次", started)
+	}
+	if n := len(runner.Calls()); n != 1 {
+		t.Fatalf("创建了 %d 个子进程，期望 1 个", n)
+	}
+}
+
+func TestReloadKeepsRuntimeState(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: svc
+    command: fake-sleep
+    enabled: true
+    description: before
+`)
+	if err := pm.StartProcess("svc"); err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+	pm.mutex.Lock()
+	pm.processes["svc"].Restarts = 3
+	pm.mutex.Unlock()
+	before := processState(t, pm, "svc")
+
+	writeTestConfig(t, pm.configPath, `
+processes:
+  - name: svc
+    command: fake-sleep
+    enabled: true
+    description: after
+    max_restarts: 20
+`)
+	if err := pm.LoadConfig(); err != nil {
+		t.Fatalf("重新加载配置失败: %v", err)
+	}
+
+	after := processState(t, pm, "svc")
+	if after.Config.Description != "after" || after.Config.MaxRestarts != 20 {
+		t.Fatalf("配置没有更新: %+v", after.Config)
+	}
+	if after.Status != before.Status || after.PID != before.PID || !after.StartTime.Equal(before.StartTime) || after.Restarts != 3 {
+		t.Fatalf("运行时状态被修改: 状态 %s→%s，PID %d→%d，启动时间 %v→%v，重启计数 3→%d",
+			before.Status, after.Status, before.PID, after.PID, before.StartTime, after.StartTime, after.Restarts)
+	}
+	if !slices.Equal(after.Output[:len(before.Output)], before.Output) {
+		t.Fatalf("日志缓冲被修改: %q → %q", before.Output, after.Output)
+	}
+}
+
+func TestRemoveRunningProcessFromConfig(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: svc
+    command: fake-sleep
+    enabled: true
+  - name: other
+    command: fake-sleep
+`)
+	if err := pm.StartProcess("svc"); err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+	pid := processState(t, pm, "svc").PID
+
+	writeTestConfig(t, pm.configPath, `
+processes:
+  - name: other
+    command: fake-sleep
+`)
+	if err := pm.LoadConfig(); err != nil {
+		t.Fatalf("重新加载配置失败: %v", err)
+	}
+	if _, exists := pm.GetProcess("svc"); exists {
+		t.Fatal("已从配置中删除的进程仍然存在")
+	}
+
+	// 进程退出后监控协程在状态已被删除的情况下完成清理，不应 panic
+	waitFor(t, "被删除的进程退出", func() bool {
+		pm.mutex.RLock()
+		defer pm.mutex.RUnlock()
+		_, running := pm.commands["svc"]
+		return !running
+	})
+	if err := syscall.Kill(pid, 0); err == nil {
+		t.Fatalf("进程 %d 仍在运行", pid)
+	}
+	if !slices.ContainsFunc(keeperLogs.Lines(0), func(line string) bool {
+		return strings.Contains(line, "进程 svc 已退出（已从配置中移除）")
+	}) {
+		t.Fatal("没有记录被删除进程的退出")
+	}
+}
+
+// outputOf 返回日志缓冲中 STDOUT、STDERR 行去掉时间戳后的内容
+func outputOf(t *testing.T, pm *ProcessManager, name string) []string {
+	t.Helper()
+	var lines []string
+	for _, line := range processState(t, pm, name).Output {
+		if i := strings.Index(line, "] STD"); i >= 0 {
+			lines = append(lines, line[i+2:])
+		}
+	}
+	return lines
+}
+
+func TestLogWriterSplitsLines(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: svc
+    command: fake-sleep
+`)
+	pump := pm.newLogPump("svc")
+	lw := &logWriter{name: "svc", pm: pm, isStdout: true, pump: pump}
+
+	// 一次写入多行，最后一行不完整，在下一次写入中补全
+	lw.Write([]byte("first\nsecond\nthi"))
+	lw.Write([]byte("rd\nfourth\nfif"))
+	lw.Flush()
+	pump.Close()
+
+	want := []string{"STDOUT: first", "STDOUT: second", "STDOUT: third", "STDOUT: fourth", "STDOUT: fif"}
+	if got := outputOf(t, pm, "svc"); !slices.Equal(got, want) {
+		t.Fatalf("日志行为 %q，期望 %q", got, want)
+	}
+}
+
+func TestLogWriterScrubsBeforeClipping(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: svc
+    command: fake-sleep
+`)
+	pump := pm.newLogPump("svc")
+	lw := &logWriter{name: "svc", pm: pm, isStdout: true, pump: pump, maxLine: 10, secrets: []string{"hunter2"}}
+
+	// 敏感值跨过截断位置：完整的行和没有换行、被缓冲截断的行都不能残留敏感值的前半部分
+	lw.Write([]byte("token=hunter2\n"))
+	lw.Write([]byte("12345678hunter2"))
+	lw.Write([]byte("xxxxxxxxxxxxxxxx"))
+	lw.Flush()
+	pump.Close()
+
+	want := []string{"STDOUT: token=***", "STDOUT: 12345678**" + truncatedLineMarker}
+	if got := outputOf(t, pm, "svc"); !slices.Equal(got, want) {
+		t.Fatalf("日志行为 %q，期望 %q", got, want)
+	}
+}
+
+func TestExitStatusDistinguishesSignals(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: clean
+    command: fake-exit
+    args: ["0"]
+    enabled: true
+  - name: killed
+    command: fake-kill
+    args: ["9"]
+    enabled: true
+  - name: terminated
+    command: fake-kill
+    args: ["15"]
+    enabled: true
+`)
+
+	tests := []struct {
+		name       string
+		exitCode   int
+		stopReason string
+		lastError  string
+	}{
+		{"clean", 0, StopReasonExited, ""},
+		{"killed", 128 + int(syscall.SIGKILL), StopReasonCrashed, describeSignal(syscall.SIGKILL)},
+		{"terminated", 128 + int(syscall.SIGTERM), StopReasonCrashed, describeSignal(syscall.SIGTERM)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := pm.StartProcess(tt.name); err != nil {
+				t.Fatalf("StartProcess: %v", err)
+			}
+			waitFor(t, "进程退出", func() bool { return processState(t, pm, tt.name).Status == "stopped" })
+
+			status := processState(t, pm, tt.name)
+			if status.LastExitCode != tt.exitCode || status.StopReason != tt.stopReason || status.LastError != tt.lastError {
+				t.Fatalf("退出码 %d，停止原因 %s，错误 %q；期望 %d，%s，%q",
+					status.LastExitCode, status.StopReason, status.LastError, tt.exitCode, tt.stopReason, tt.lastError)
+			}
+		})
+	}
+}
+
+func TestEmptyArgumentsReachCommand(t *testing.T) {
+	pm, runner := newTestManager(t, `
+processes:
+  - name: keep
+    command: fake-args
+    args: ["-i", "", "file"]
+    enabled: true
+  - name: trim
+    command: fake-args
+    args: ["-i", "", "file"]
+    enabled: true
+    trim_empty_args: true
+`)
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"keep", []string{"-i", "", "file"}},
+		{"trim", []string{"-i", "file"}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := pm.StartProcess(tt.name); err != nil {
+				t.Fatalf("StartProcess: %v", err)
+			}
+			if got := runner.Calls()[i][1:]; !slices.Equal(got, tt.want) {
+				t.Fatalf("传给命令的参数为 %q，期望 %q", got, tt.want)
+			}
+
+			// 子进程实际收到的参数，fake-args 每行输出一个带引号的参数
+			waitFor(t, "进程退出", func() bool { return processState(t, pm, tt.name).Status == "stopped" })
+			var want []string
+			for _, arg := range tt.want {
+				want = append(want, fmt.Sprintf("STDOUT: %q", arg))
+			}
+			if got := outputOf(t, pm, tt.name); !slices.Equal(got, want) {
+				t.Fatalf("子进程收到的参数为 %q，期望 %q", got, want)
+			}
+		})
+	}
+}
+
+func TestBuildSudoArgsKeepsEmptyArguments(t *testing.T) {
+	config := ProcessConfig{Command: "/usr/bin/sed", User: "app", Args: []string{"-i", "", "file"}}
+	want := []string{"-u", "app", "/usr/bin/sed", "-i", "", "file"}
+	if got := buildSudoArgs(config); !slices.Equal(got, want) {
+		t.Fatalf("sudo 参数为 %q，期望 %q", got, want)
+	}
+
+	config.TrimEmptyArgs = true
+	want = []string{"-u", "app", "/usr/bin/sed", "-i", "file"}
+	if got := buildSudoArgs(config); !slices.Equal(got, want) {
+		t.Fatalf("trim_empty_args 时 sudo 参数为 %q，期望 %q", got, want)
+	}
+}
+
+func TestUmaskAppliesOnlyToChild(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: job
+    command: sh
+    args: ["-c", "umask; echo \"[$1]\"", "sh", ""]
+    enabled: true
+    umask: "027"
+`)
+	before := syscall.Umask(0022)
+	syscall.Umask(before)
+
+	if err := pm.StartProcess("job"); err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+	waitFor(t, "进程退出", func() bool { return processState(t, pm, "job").Status == "stopped" })
+
+	// 子进程使用配置的 umask，参数（包括空字符串）原样传给原命令
+	if got, want := outputOf(t, pm, "job"), []string{"STDOUT: 0027", "STDOUT: []"}; !slices.Equal(got, want) {
+		t.Fatalf("输出为 %q，期望 %q", got, want)
+	}
+	after := syscall.Umask(before)
+	if after != before {
+		t.Fatalf("keeper 的 umask 从 %04o 变为 %04o", before, after)
+	}
+}
+
+func TestCopyProcessConfigIsDeep(t *testing.T) {
+	autoStart := true
+	config := ProcessConfig{Watch: []string{"src", "templates"}, AutoStart: &autoStart}
+	copied := copyProcessConfig(config)
+	copied.Watch[0] = "changed"
+	if config.Watch[0] != "src" {
+		t.Fatalf("修改副本的 watch 影响了原配置: %q", config.Watch)
+	}
+	*copied.AutoStart = false
+	if !config.autoStarts() {
+		t.Fatal("修改副本的 auto_start 影响了原配置")
+	}
+}
+
+func TestUnknownAPIRequestsReturnJSON(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: web
+    command: fake-sleep
+`)
+	handler := pm.routes()
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		allow  string
+	}{
+		{http.MethodPost, "/api/nothing", http.StatusNotFound, ""},
+		{http.MethodDelete, "/api/nothing", http.StatusNotFound, ""},
+		{http.MethodGet, "/api/nothing", http.StatusNotFound, ""},
+		{http.MethodDelete, "/api/status", http.StatusMethodNotAllowed, "GET"},
+		{http.MethodPut, "/api/reload", http.StatusMethodNotAllowed, "POST"},
+		{http.MethodGet, "/api/reload", http.StatusMethodNotAllowed, "POST"},
+		{http.MethodPut, "/api/logs/web", http.StatusMethodNotAllowed, "GET, DELETE"},
+	}
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(tt.method, tt.path, nil))
+		var body struct {
+			Success bool   `json:"success"`
+			Error   string `json:"error"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil || body.Success || body.Error == "" {
+			t.Errorf("%s %s 返回的不是 JSON 错误: %q", tt.method, tt.path, recorder.Body.String())
+		}
+		if recorder.Code != tt.code || recorder.Header().Get("Allow") != tt.allow {
+			t.Errorf("%s %s 返回 %d，Allow %q，期望 %d，Allow %q", tt.method, tt.path, recorder.Code, recorder.Header().Get("Allow"), tt.code, tt.allow)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// on_max_restarts 的取值：达到重启次数上限（max_restarts）后的处理方式
+const (
+	MaxRestartsDisable   = "disable"    // 禁用自动重启（默认）
+	MaxRestartsSlowRetry = "slow-retry" // 改为按 slow_retry_interval 的固定间隔继续重试
+	MaxRestartsAlertOnly = "alert-only" // 只发出告警，按 restart_delay 继续正常重试
+)
+
+// defaultSlowRetryInterval 未配置 slow_retry_interval 时 slow-retry 的重试间隔
+const defaultSlowRetryInterval = 5 * time.Minute
+
+// validateMaxRestartsPolicy 校验 on_max_restarts
+func validateMaxRestartsPolicy(policy string) error {
+	switch policy {
+	case "", MaxRestartsDisable, MaxRestartsSlowRetry, MaxRestartsAlertOnly:
+		return nil
+	default:
+		return fmt.Errorf("未知的 on_max_restarts: %s，支持 disable, slow-retry, alert-only", policy)
+	}
+}
+
+// disablesOnMaxRestarts 判断达到重启次数上限时是否禁用进程
+func disablesOnMaxRestarts(config ProcessConfig) bool {
+	return config.OnMaxRestarts == "" || config.OnMaxRestarts == MaxRestartsDisable
+}
+
+// slowRetryInterval 返回 slow-retry 的重试间隔
+func slowRetryInterval(config ProcessConfig) time.Duration {
+	if config.SlowRetryInterval > 0 {
+		return time.Duration(config.SlowRetryInterval) * time.Second
+	}
+	return defaultSlowRetryInterval
+}
+
+// alertMaxRestarts 达到重启次数上限时告警：记录错误日志并执行 alert_command。
+// 重启计数重置（或滑出 restart_window）之前只告警一次。调用方需持有 mutex
+func (pm *ProcessManager) alertMaxRestarts(name string, status *ProcessStatus) {
+	if status.restartAlerted {
+		return
+	}
+	status.restartAlerted = true
+
+	policy := status.Config.OnMaxRestarts
+	if policy == "" {
+		policy = MaxRestartsDisable
+	}
+	logErrorf("告警: 进程 %s 重启次数达到上限 (%d次)，on_max_restarts: %s", name, status.Restarts, policy)
+	if status.Config.AlertCommand == "" {
+		return
+	}
+
+	go pm.runAlertCommand(name, copyProcessConfig(status.Config), map[string]string{
+		"KEEPER_ALERT":           "max_restarts",
+		"KEEPER_RESTARTS":        fmt.Sprint(status.Restarts),
+		"KEEPER_ON_MAX_RESTARTS": policy,
+	})
+}
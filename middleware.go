@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statusRecorder 记录处理器写入的状态码和响应字节数
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap 供 http.ResponseController 访问底层 ResponseWriter
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// isReadOnlyMethod 判断请求方法是否只读
+func isReadOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// clientAddress 返回请求的来源地址（不含端口），Unix 套接字连接返回 "unix"
+func clientAddress(r *http.Request) string {
+	if r.RemoteAddr == "" || r.RemoteAddr == "@" {
+		return "unix"
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// accessLog 记录每个请求的方法、路径、来源地址、状态码和耗时。
+// 修改状态的请求（非 GET/HEAD）以 info 级别记录便于审计，页面轮询等只读请求以 debug 级别记录
+func accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		remote := r.RemoteAddr
+		if remote == "" || remote == "@" {
+			remote = "unix"
+		}
+
+		format := "访问 %s %s 来源 %s 状态 %d 大小 %d 耗时 %v"
+		args := []interface{}{r.Method, r.URL.RequestURI(), remote, status, recorder.bytes, time.Since(start).Round(time.Microsecond)}
+		if isReadOnlyMethod(r.Method) {
+			logDebugf(format, args...)
+		} else {
+			logInfof(format, args...)
+		}
+	})
+}
+
+// readOnlyGuard 只读模式下拒绝所有修改状态的请求（非 GET/HEAD），返回 403。
+// 每次请求时读取配置，修改配置文件后由定期检查重新加载生效
+func (pm *ProcessManager) readOnlyGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pm.mutex.RLock()
+		readOnly := pm.config != nil && pm.config.Server.ReadOnly
+		pm.mutex.RUnlock()
+
+		if !readOnly || isReadOnlyMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			http.Error(w, "只读模式，不允许修改", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "只读模式，不允许修改",
+		})
+	})
+}
+
+// rateLimit 按来源地址限制请求频率，超过限制返回 429。
+// 只读请求和修改状态的请求分别计数，限制值每次请求时读取，重新加载配置后立即生效
+func (pm *ProcessManager) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		kind := "read"
+		var rate float64
+		var burst int
+		pm.mutex.RLock()
+		if pm.config != nil {
+			rate, burst = pm.config.Server.RateLimit, pm.config.Server.RateLimitBurst
+			if !isReadOnlyMethod(r.Method) {
+				kind = "control"
+				rate = pm.config.Server.ControlRateLimit
+			}
+		}
+		pm.mutex.RUnlock()
+
+		if burst <= 0 {
+			burst = int(math.Ceil(rate))
+		}
+		if pm.apiLimiter.allow(kind+" "+clientAddress(r), rate, burst, time.Now()) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(1/rate))))
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			http.Error(w, "请求过于频繁，请稍后再试", http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "请求过于频繁，请稍后再试",
+		})
+	})
+}
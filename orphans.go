@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// runningFileName 状态目录中记录配置了 on_orphan 的运行中进程的文件，进程启动和退出时更新
+const runningFileName = "running.json"
+
+// on_orphan 的取值：上一个 keeper 异常退出（或未启用 detach_on_exit 退出）后遗留的进程在下次启动时的处理方式
+const (
+	OrphanIgnore = "ignore" // 不处理（默认），可能与新启动的进程同时运行
+	OrphanKill   = "kill"   // 按 stop_sequence 停止遗留的进程，之后正常启动
+	OrphanAdopt  = "adopt"  // 接管遗留的进程，不再启动新的进程
+)
+
+// validateOrphanPolicy 校验 on_orphan
+func validateOrphanPolicy(policy string) error {
+	switch policy {
+	case "", OrphanIgnore, OrphanKill, OrphanAdopt:
+		return nil
+	default:
+		return fmt.Errorf("未知的 on_orphan: %s，支持 kill, adopt, ignore", policy)
+	}
+}
+
+// tracksOrphans 判断进程运行时是否需要记录 PID，以便 keeper 异常退出后处理遗留的进程
+func tracksOrphans(config ProcessConfig) bool {
+	return config.OnOrphan == OrphanKill || config.OnOrphan == OrphanAdopt
+}
+
+// recordRunning 把配置了 on_orphan 的运行中进程写入状态目录，没有这样的进程时删除文件。调用者需持有锁
+func (pm *ProcessManager) recordRunning() {
+	running := make(map[string]DetachedProcess)
+	for name := range pm.commands {
+		status, exists := pm.processes[name]
+		if !exists || status.PID == 0 || !tracksOrphans(status.Config) {
+			continue
+		}
+		running[name] = DetachedProcess{
+			PID:         status.PID,
+			StartTime:   status.StartTime,
+			CommandLine: readCommandLine(status.PID),
+			Restarts:    status.Restarts,
+		}
+	}
+
+	path := filepath.Join(pm.stateDir(), runningFileName)
+	if len(running) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logWarnf("删除运行记录 %s 失败: %v", path, err)
+		}
+		return
+	}
+
+	data, err := json.MarshalIndent(running, "", "  ")
+	if err != nil {
+		logWarnf("序列化运行记录失败: %v", err)
+		return
+	}
+	if err := os.MkdirAll(pm.stateDir(), 0755); err != nil {
+		logWarnf("创建状态目录失败: %v", err)
+		return
+	}
+	// 先写临时文件再改名，keeper 在写入过程中退出也不会留下不完整的记录
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		logWarnf("写入运行记录失败: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		logWarnf("写入运行记录失败: %v", err)
+	}
+}
+
+// ReconcileOrphans 在启动进程之前读取上次运行记录的 PID，按各进程的 on_orphan 处理 keeper 异常退出后遗留的进程。
+// 已通过分离状态接管的进程不再处理；PID 已退出或命令行与记录不符（PID 被复用）时忽略
+func (pm *ProcessManager) ReconcileOrphans() {
+	path := filepath.Join(pm.stateDir(), runningFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logWarnf("读取运行记录失败: %v", err)
+		}
+		return
+	}
+	var running map[string]DetachedProcess
+	if err := json.Unmarshal(data, &running); err != nil {
+		logWarnf("解析运行记录 %s 失败: %v", path, err)
+		return
+	}
+
+	type orphan struct {
+		name   string
+		pid    int
+		config ProcessConfig
+	}
+	var kills []orphan
+
+	pm.mutex.Lock()
+	for name, record := range running {
+		if _, adopted := pm.commands[name]; adopted {
+			continue
+		}
+		if !processAlive(record.PID) {
+			continue
+		}
+		if record.CommandLine != nil && !slices.Equal(readCommandLine(record.PID), record.CommandLine) {
+			logInfof("PID %d 的命令行与记录不符，可能已被复用，不作为进程 %s 的遗留进程处理", record.PID, name)
+			continue
+		}
+
+		status, exists := pm.processes[name]
+		if !exists {
+			logWarnf("发现已不在配置中的进程 %s 遗留的 PID %d，不处理", name, record.PID)
+			continue
+		}
+		switch status.Config.OnOrphan {
+		case OrphanKill:
+			pm.addLog(name, fmt.Sprintf("WARNING: 发现 keeper 上次异常退出时遗留的进程 %d，按 on_orphan 停止", record.PID))
+			logWarnf("进程 %s 遗留的 PID %d 仍在运行，停止该进程", name, record.PID)
+			kills = append(kills, orphan{name: name, pid: record.PID, config: status.Config})
+		case OrphanAdopt:
+			pm.addLog(name, fmt.Sprintf("INFO: 发现 keeper 上次异常退出时遗留的进程 %d，按 on_orphan 接管", record.PID))
+			pm.adopt(name, status, record)
+		default:
+			logWarnf("进程 %s 遗留的 PID %d 仍在运行，on_orphan 为 ignore，不处理", name, record.PID)
+		}
+	}
+	pm.recordRunning()
+	pm.mutex.Unlock()
+
+	// 停止遗留进程时不持有锁，全部停止后才开始启动进程，避免与新进程同时运行
+	var wg sync.WaitGroup
+	for _, o := range kills {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			forced := killOrphan(o.pid, o.config)
+
+			pm.mutex.Lock()
+			defer pm.mutex.Unlock()
+			if forced {
+				pm.addLog(o.name, fmt.Sprintf("WARNING: 遗留的进程 %d 未响应停止信号，已强制杀死", o.pid))
+			} else {
+				pm.addLog(o.name, fmt.Sprintf("INFO: 遗留的进程 %d 已停止", o.pid))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// killOrphan 按 stop_sequence 向遗留的进程发送信号，全部步骤后仍未退出时发送 SIGKILL，返回是否被强制杀死
+func killOrphan(pid int, config ProcessConfig) bool {
+	group := ownsProcessGroup(config.ProcessGroup)
+	for _, step := range stopSteps(config) {
+		signalProcess(pid, step.signal, group)
+		deadline := time.Now().Add(step.wait)
+		for processAlive(pid) && time.Now().Before(deadline) {
+			time.Sleep(pidFilePollInterval)
+		}
+		if !processAlive(pid) {
+			return false
+		}
+	}
+
+	signalProcess(pid, syscall.SIGKILL, group)
+	for i := 0; processAlive(pid) && i < 25; i++ {
+		time.Sleep(pidFilePollInterval)
+	}
+	return true
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// stdout_file、stderr_file 的打开方式
+const (
+	OutputFileAppend   = "append"   // 追加到已有内容之后
+	OutputFileTruncate = "truncate" // 每次启动时清空
+)
+
+// defaultOutputFileBackups 配置了 output_file_max_size 但未配置 output_file_max_backups 时保留的轮转文件数
+const defaultOutputFileBackups = 5
+
+// outputFileSet 进程的标准输出和标准错误额外写入的文件，两者路径相同时共用一个文件
+type outputFileSet struct {
+	stdout *outputFile
+	stderr *outputFile
+}
+
+// outputFile stdout_file 或 stderr_file 打开的文件，配置了 output_file_max_size 时超过大小后轮转
+type outputFile struct {
+	mutex    sync.Mutex // 标准输出和标准错误共用一个文件时，两个复制协程会并发写入
+	path     string
+	file     *os.File
+	size     int64 // 当前文件的字节数
+	maxSize  int64 // 超过该大小后轮转，0 表示不轮转
+	backups  int   // 保留的轮转文件数
+	compress bool  // 轮转后的文件是否以 gzip 压缩
+
+	renamed    bool       // 当前文件已改名为 path.1 但重新创建 path 失败，下次轮转只需重试创建
+	compressed chan error // 后台压缩上一个轮转文件的结果，没有进行中的压缩时为 nil
+}
+
+// parseOutputFileMode 校验 output_file_mode，返回是否需要清空文件，空字符串为 append
+func parseOutputFileMode(mode string) (bool, error) {
+	switch mode {
+	case "", OutputFileAppend:
+		return false, nil
+	case OutputFileTruncate:
+		return true, nil
+	default:
+		return false, fmt.Errorf("未知的 output_file_mode: %s，支持 append, truncate", mode)
+	}
+}
+
+// openOutputFiles 打开 stdout_file 和 stderr_file，相对路径相对于配置文件所在目录。
+// 都未配置时返回 nil；接管分离的进程时 adopt 为 true，始终追加，不清空进程已写入的内容
+func (pm *ProcessManager) openOutputFiles(config ProcessConfig, adopt bool) (*outputFileSet, error) {
+	if config.StdoutFile == "" && config.StderrFile == "" {
+		return nil, nil
+	}
+
+	truncate, _ := parseOutputFileMode(config.OutputFileMode)
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if truncate && !adopt {
+		flags |= os.O_TRUNC
+	}
+
+	stdoutPath, stderrPath := pm.outputFilePath(config.StdoutFile), pm.outputFilePath(config.StderrFile)
+
+	files := &outputFileSet{}
+	if stdoutPath != "" {
+		file, err := openOutputFile(stdoutPath, flags, config)
+		if err != nil {
+			return nil, fmt.Errorf("打开 stdout_file 失败: %v", err)
+		}
+		files.stdout = file
+	}
+	switch {
+	case stderrPath == "":
+	case stderrPath == stdoutPath:
+		files.stderr = files.stdout
+	default:
+		file, err := openOutputFile(stderrPath, flags, config)
+		if err != nil {
+			files.Close()
+			return nil, fmt.Errorf("打开 stderr_file 失败: %v", err)
+		}
+		files.stderr = file
+	}
+	return files, nil
+}
+
+// outputFilePath 返回 stdout_file 或 stderr_file 的实际路径，相对路径相对于配置文件所在目录
+func (pm *ProcessManager) outputFilePath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(configDir(pm.configPath), path)
+}
+
+// truncateOutputFiles 清空进程的 stdout_file 和 stderr_file（不包括轮转文件），
+// files 为进程运行中打开的文件，为 nil 时按路径清空
+func (pm *ProcessManager) truncateOutputFiles(config ProcessConfig, files *outputFileSet) error {
+	if files != nil {
+		for _, f := range []*outputFile{files.stdout, files.stderr} {
+			if f != nil {
+				if err := f.Truncate(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for _, path := range []string{config.StdoutFile, config.StderrFile} {
+		if path == "" {
+			continue
+		}
+		if err := os.Truncate(pm.outputFilePath(path), 0); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// openOutputFile 打开输出文件并记录当前大小，用于判断何时轮转
+func openOutputFile(path string, flags int, config ProcessConfig) (*outputFile, error) {
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	backups := config.OutputFileMaxBackups
+	if backups == 0 {
+		backups = defaultOutputFileBackups
+	}
+	return &outputFile{
+		path:     path,
+		file:     file,
+		size:     info.Size(),
+		maxSize:  config.OutputFileMaxSize,
+		backups:  backups,
+		compress: config.OutputFileCompress,
+	}, nil
+}
+
+// Write 写入文件，写入后会超过 output_file_max_size 时先轮转。
+// 轮转失败时继续写入当前文件，同时返回错误以便在进程状态中显示
+func (f *outputFile) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	rotateErr := f.compressResult(false)
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			rotateErr = err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err == nil && rotateErr != nil {
+		err = fmt.Errorf("轮转输出文件失败: %v", rotateErr)
+	}
+	return n, err
+}
+
+// backupPath 返回第 i 个轮转文件的路径，数字越大越旧
+func (f *outputFile) backupPath(i int, suffix string) string {
+	return fmt.Sprintf("%s.%d%s", f.path, i, suffix)
+}
+
+// rotate 把当前文件改名为 path.1（启用压缩时在后台压缩为 path.1.gz），已有的轮转文件依次后移，
+// 超出保留数量的最旧文件被覆盖，然后重新创建 path 继续写入。调用方需持有 f.mutex
+func (f *outputFile) rotate() error {
+	first := f.backupPath(1, "")
+	if !f.renamed {
+		// 上一个轮转文件还在压缩时先等待完成，否则后移会改动正在压缩的文件
+		if err := f.compressResult(true); err != nil {
+			logWarnf("%v", err)
+		}
+		for i := f.backups - 1; i >= 1; i-- {
+			for _, suffix := range []string{"", ".gz"} {
+				if err := os.Rename(f.backupPath(i, suffix), f.backupPath(i+1, suffix)); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+		}
+		if err := os.Rename(f.path, first); err != nil {
+			return err
+		}
+		f.renamed = true
+	}
+
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0644)
+	if err != nil {
+		return err // 继续写入已改名为 path.1 的文件，下次写入时只重试创建，不再后移轮转文件
+	}
+	f.file.Close()
+	f.file = file
+	f.size = 0
+	f.renamed = false
+
+	// 压缩可能较慢，在后台进行，不阻塞进程输出
+	if f.compress {
+		result := make(chan error, 1)
+		f.compressed = result
+		go func() {
+			result <- compressFile(first, f.backupPath(1, ".gz"))
+		}()
+	}
+	return nil
+}
+
+// compressResult 返回后台压缩的错误，wait 为 false 时压缩尚未完成则返回 nil。调用方需持有 f.mutex
+func (f *outputFile) compressResult(wait bool) error {
+	if f.compressed == nil {
+		return nil
+	}
+	var err error
+	if wait {
+		err = <-f.compressed
+	} else {
+		select {
+		case err = <-f.compressed:
+		default:
+			return nil
+		}
+	}
+	f.compressed = nil
+	if err != nil {
+		return fmt.Errorf("压缩轮转文件 %s 失败: %v", f.backupPath(1, ""), err)
+	}
+	return nil
+}
+
+// compressFile 把文件以 gzip 压缩为 dst，成功后删除原文件
+func compressFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
+
+// Truncate 清空当前文件，并把写入位置移回文件开头，之后的写入从头开始，不会在文件开头留下空洞
+func (f *outputFile) Truncate() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	f.size = 0
+	return nil
+}
+
+// Close 关闭文件
+func (f *outputFile) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.file.Close()
+}
+
+// attach 把文件设置为输出的额外写入目标，原样写入进程输出（不做脱敏和过滤）
+func (f *outputFileSet) attach(stdout, stderr *logWriter) {
+	if f == nil {
+		return
+	}
+	if f.stdout != nil {
+		stdout.sink = f.stdout
+	}
+	if f.stderr != nil {
+		stderr.sink = f.stderr
+	}
+}
+
+// Close 关闭打开的文件，nil 时不做任何操作
+func (f *outputFileSet) Close() {
+	if f == nil {
+		return
+	}
+	if f.stdout != nil {
+		f.stdout.Close()
+	}
+	if f.stderr != nil && f.stderr != f.stdout {
+		f.stderr.Close()
+	}
+}
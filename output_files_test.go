@@ -0,0 +1,222 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestOutputFile 在临时目录中打开输出文件
+func newTestOutputFile(t *testing.T, config ProcessConfig) *outputFile {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "out.log")
+	f, err := openOutputFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+// readGzip 读取 gzip 文件的内容
+func readGzip(t *testing.T, path string) string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestOutputFileCompressesInBackground(t *testing.T) {
+	f := newTestOutputFile(t, ProcessConfig{OutputFileMaxSize: 10, OutputFileCompress: true})
+
+	for _, line := range []string{"first-1\n", "second\n", "third-3\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+	}
+
+	// 第二次轮转前会等待第一次压缩完成，两个轮转文件都已压缩
+	f.mutex.Lock()
+	err := f.compressResult(true)
+	f.mutex.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readGzip(t, f.backupPath(2, ".gz")); got != "first-1\n" {
+		t.Fatalf("path.2.gz 内容为 %q", got)
+	}
+	if got := readGzip(t, f.backupPath(1, ".gz")); got != "second\n" {
+		t.Fatalf("path.1.gz 内容为 %q", got)
+	}
+	for _, i := range []int{1, 2} {
+		if _, err := os.Stat(f.backupPath(i, "")); !os.IsNotExist(err) {
+			t.Fatalf("压缩后未删除 %s", f.backupPath(i, ""))
+		}
+	}
+	if data, _ := os.ReadFile(f.path); string(data) != "third-3\n" {
+		t.Fatalf("当前文件内容为 %q", data)
+	}
+}
+
+func TestOutputFileRetriesReopenWithoutShifting(t *testing.T) {
+	f := newTestOutputFile(t, ProcessConfig{OutputFileMaxSize: 10})
+	f.Write([]byte("current\n"))
+
+	// 模拟轮转时改名成功但重新创建失败：当前文件已是 path.1，path 被目录占用无法打开
+	if err := os.Rename(f.path, f.backupPath(1, "")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(f.path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	f.renamed = true
+
+	for range 3 {
+		if _, err := f.Write([]byte("more\n")); err == nil || !strings.Contains(err.Error(), "轮转输出文件失败") {
+			t.Fatalf("重新创建失败时写入返回 %v", err)
+		}
+	}
+	// 每次写入只重试创建，不会把 path.1 反复后移，内容继续写入 path.1
+	if _, err := os.Stat(f.backupPath(2, "")); !os.IsNotExist(err) {
+		t.Fatal("重试时轮转文件被后移")
+	}
+	if data, _ := os.ReadFile(f.backupPath(1, "")); string(data) != "current\nmore\nmore\nmore\n" {
+		t.Fatalf("path.1 内容为 %q", data)
+	}
+
+	// path 恢复可用后重新创建并继续写入
+	os.Remove(f.path)
+	if _, err := f.Write([]byte("after\n")); err != nil {
+		t.Fatalf("恢复后写入失败: %v", err)
+	}
+	if data, _ := os.ReadFile(f.path); string(data) != "after\n" {
+		t.Fatalf("当前文件内容为 %q", data)
+	}
+	if _, err := os.Stat(f.backupPath(2, "")); !os.IsNotExist(err) {
+		t.Fatal("恢复时轮转文件被后移")
+	}
+}
+
+func TestClearLogsTruncatesOutputFiles(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: svc
+    command: fake-echo
+    args: ["hello"]
+    enabled: true
+    stdout_file: svc.out
+`)
+	path := pm.outputFilePath("svc.out")
+
+	if err := pm.StartProcess("svc"); err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+	waitFor(t, "进程退出", func() bool { return processState(t, pm, "svc").Status == "stopped" })
+	if data, _ := os.ReadFile(path); string(data) != "hello\n" {
+		t.Fatalf("stdout_file 内容为 %q", data)
+	}
+
+	if err := pm.ClearLogs("svc"); err != nil {
+		t.Fatalf("ClearLogs: %v", err)
+	}
+	if len(processState(t, pm, "svc").Output) != 0 {
+		t.Fatal("日志缓冲没有清空")
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() != 0 {
+		t.Fatalf("stdout_file 没有清空: %v", err)
+	}
+}
+
+func TestOutputFileTruncateWhileOpen(t *testing.T) {
+	f := newTestOutputFile(t, ProcessConfig{})
+	f.Write([]byte("old\n"))
+
+	// 运行中的进程继续写入，清空后从文件开头写
+	if err := f.Truncate(); err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("new\n"))
+	if data, _ := os.ReadFile(f.path); string(data) != "new\n" {
+		t.Fatalf("文件内容为 %q", data)
+	}
+}
+
+func TestTruncateModeKeepsAppending(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: svc
+    command: fake-sleep
+`)
+	path := pm.outputFilePath("svc.out")
+	if err := os.WriteFile(path, []byte("previous run\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := pm.openOutputFiles(ProcessConfig{StdoutFile: "svc.out", OutputFileMode: OutputFileTruncate}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer files.stdout.Close()
+
+	// 启动时清空；之后像 logrotate copytruncate 那样从外部清空，写入仍从文件末尾开始，不留下空洞
+	files.stdout.Write([]byte("first\n"))
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	files.stdout.Write([]byte("second\n"))
+	if data, _ := os.ReadFile(path); string(data) != "second\n" {
+		t.Fatalf("文件内容为 %q", data)
+	}
+}
+
+func TestClearLogsTruncateModeFile(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: svc
+    command: fake-sleep
+`)
+	path := pm.outputFilePath("svc.out")
+	files, err := pm.openOutputFiles(ProcessConfig{StdoutFile: "svc.out", OutputFileMode: OutputFileTruncate}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer files.stdout.Close()
+
+	// 运行中的进程在清空日志后继续写入
+	files.stdout.Write([]byte("before clear\n"))
+	if err := files.stdout.Truncate(); err != nil {
+		t.Fatal(err)
+	}
+	files.stdout.Write([]byte("after\n"))
+	if data, _ := os.ReadFile(path); string(data) != "after\n" {
+		t.Fatalf("文件内容为 %q", data)
+	}
+
+	// 没有以 O_APPEND 打开的文件同样从开头写入
+	other, err := openOutputFile(filepath.Join(t.TempDir(), "plain.log"), os.O_WRONLY|os.O_CREATE, ProcessConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	other.Write([]byte("before clear\n"))
+	if err := other.Truncate(); err != nil {
+		t.Fatal(err)
+	}
+	other.Write([]byte("after\n"))
+	if data, _ := os.ReadFile(other.path); string(data) != "after\n" {
+		t.Fatalf("文件内容为 %q", data)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// placeholderPattern 匹配 %(name)s 形式的占位符
+var placeholderPattern = regexp.MustCompile(`%\(([A-Za-z0-9_]+)\)s`)
+
+// placeholderVars 构建占位符取值：
+//
+//	%(process_name)s 进程名称
+//	%(hostname)s     主机名
+//	%(here)s         配置文件所在目录
+//	%(ENV_XXX)s      环境变量 XXX，进程的 environment 优先于 keeper 自身的环境
+func placeholderVars(config ProcessConfig, configPath string) map[string]string {
+	vars := map[string]string{
+		"process_name": config.Name,
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		vars["hostname"] = hostname
+	}
+	if dir, err := filepath.Abs(configDir(configPath)); err == nil {
+		vars["here"] = dir
+	}
+	for _, entry := range os.Environ() {
+		key, value, _ := strings.Cut(entry, "=")
+		vars["ENV_"+key] = value
+	}
+	for key, value := range config.Environment {
+		vars["ENV_"+key] = value
+	}
+	return vars
+}
+
+// expandPlaceholders 替换字符串中的占位符，存在未知占位符时返回错误
+func expandPlaceholders(value string, vars map[string]string) (string, error) {
+	var unknown []string
+	result := placeholderPattern.ReplaceAllStringFunc(value, func(match string) string {
+		key := placeholderPattern.FindStringSubmatch(match)[1]
+		replacement, ok := vars[key]
+		if !ok {
+			unknown = append(unknown, match)
+			return match
+		}
+		return replacement
+	})
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("未知的占位符: %s", strings.Join(unknown, ", "))
+	}
+	return result, nil
+}
+
+// resolvePlaceholders 返回 command、args、workdir、stdin_file、stdout_file、stderr_file、lock_file、pre_start、post_stop 和 alert_command 中占位符已被替换的进程配置副本
+func resolvePlaceholders(config ProcessConfig, configPath string) (ProcessConfig, error) {
+	vars := placeholderVars(config, configPath)
+
+	command, err := expandPlaceholders(config.Command, vars)
+	if err != nil {
+		return config, fmt.Errorf("command %v", err)
+	}
+	workDir, err := expandPlaceholders(config.WorkDir, vars)
+	if err != nil {
+		return config, fmt.Errorf("workdir %v", err)
+	}
+	stdinFile, err := expandPlaceholders(config.StdinFile, vars)
+	if err != nil {
+		return config, fmt.Errorf("stdin_file %v", err)
+	}
+	stdoutFile, err := expandPlaceholders(config.StdoutFile, vars)
+	if err != nil {
+		return config, fmt.Errorf("stdout_file %v", err)
+	}
+	stderrFile, err := expandPlaceholders(config.StderrFile, vars)
+	if err != nil {
+		return config, fmt.Errorf("stderr_file %v", err)
+	}
+	lockFile, err := expandPlaceholders(config.LockFile, vars)
+	if err != nil {
+		return config, fmt.Errorf("lock_file %v", err)
+	}
+	preStart, err := expandPlaceholders(config.PreStart, vars)
+	if err != nil {
+		return config, fmt.Errorf("pre_start %v", err)
+	}
+	postStop, err := expandPlaceholders(config.PostStop, vars)
+	if err != nil {
+		return config, fmt.Errorf("post_stop %v", err)
+	}
+	alertCommand, err := expandPlaceholders(config.AlertCommand, vars)
+	if err != nil {
+		return config, fmt.Errorf("alert_command %v", err)
+	}
+	args := make([]string, len(config.Args))
+	for i, arg := range config.Args {
+		if args[i], err = expandPlaceholders(arg, vars); err != nil {
+			return config, fmt.Errorf("args[%d] %v", i, err)
+		}
+	}
+
+	config.Command = command
+	config.WorkDir = workDir
+	config.StdinFile = stdinFile
+	config.StdoutFile = stdoutFile
+	config.StderrFile = stderrFile
+	config.LockFile = lockFile
+	config.PreStart = preStart
+	config.PostStop = postStop
+	config.AlertCommand = alertCommand
+	if config.Args != nil {
+		config.Args = args
+	}
+	return config, nil
+}
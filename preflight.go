@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// PreflightResult 进程启动前检查的结果
+type PreflightResult struct {
+	ExecutableFound bool      `json:"executable_found"`
+	Executable      string    `json:"executable"`     // 占位符替换后实际要执行的命令
+	WorkDirFound    bool      `json:"workdir_found"`  // 未配置 workdir 或启用 create_workdir 时为 true
+	Note            string    `json:"note,omitempty"` // 检查未通过的原因，多条以分号分隔
+	CheckedAt       time.Time `json:"checked_at"`
+}
+
+// OK 判断检查是否全部通过
+func (r *PreflightResult) OK() bool {
+	return r.ExecutableFound && r.WorkDirFound
+}
+
+// preflight 检查进程的可执行文件和工作目录是否可用，不修改任何状态
+func (pm *ProcessManager) preflight(config ProcessConfig) *PreflightResult {
+	result := &PreflightResult{Executable: config.Command, CheckedAt: time.Now()}
+
+	resolved, err := resolvePlaceholders(config, pm.configPath)
+	if err != nil {
+		result.Note = fmt.Sprintf("占位符无效: %v", err)
+		return result
+	}
+	resolved = applyShell(resolved)
+	result.Executable = resolved.Command
+
+	var notes []string
+	if err := pm.checkExecutable(resolved.Command); err != nil {
+		notes = append(notes, err.Error())
+	} else {
+		result.ExecutableFound = true
+	}
+
+	result.WorkDirFound = true
+	if resolved.WorkDir != "" && !resolved.CreateWorkDir {
+		if _, err := os.Stat(resolved.WorkDir); os.IsNotExist(err) {
+			result.WorkDirFound = false
+			notes = append(notes, fmt.Sprintf("工作目录不存在: %s", resolved.WorkDir))
+		}
+	}
+
+	result.Note = strings.Join(notes, "; ")
+	return result
+}
+
+// runPreflightLocked 重新检查所有进程并保存到进程状态，调用者需持有锁
+func (pm *ProcessManager) runPreflightLocked() {
+	for _, status := range pm.processes {
+		status.Preflight = pm.preflight(status.Config)
+	}
+}
+
+// RunPreflight 重新检查所有进程，返回按进程名称索引的结果
+func (pm *ProcessManager) RunPreflight() map[string]*PreflightResult {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	pm.runPreflightLocked()
+	results := make(map[string]*PreflightResult, len(pm.processes))
+	for name, status := range pm.processes {
+		results[name] = status.Preflight
+	}
+	return results
+}
+
+// 启动前检查 API
+func (pm *ProcessManager) handlePreflight(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	results := pm.RunPreflight()
+	failed := 0
+	for _, result := range results {
+		if !result.OK() {
+			failed++
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"results": results,
+		"failed":  failed,
+	})
+}
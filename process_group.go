@@ -0,0 +1,39 @@
+package main
+
+import (
+	"syscall"
+)
+
+// 进程组模式
+const (
+	ProcessGroupOwn     = "own"     // 子进程使用自己的进程组（默认），停止时向整个进程组发送信号
+	ProcessGroupSession = "session" // 子进程创建新会话，完全脱离 keeper 的控制终端
+	ProcessGroupInherit = "inherit" // 子进程留在 keeper 的进程组中，信号只发给子进程本身
+)
+
+// processSysProcAttr 根据进程组模式生成子进程属性
+func processSysProcAttr(mode string) *syscall.SysProcAttr {
+	switch mode {
+	case ProcessGroupSession:
+		// 新会话的首进程同时是新进程组的组长
+		return &syscall.SysProcAttr{Setsid: true}
+	case ProcessGroupInherit:
+		return &syscall.SysProcAttr{}
+	default:
+		return &syscall.SysProcAttr{Setpgid: true, Pgid: 0}
+	}
+}
+
+// ownsProcessGroup 判断该模式下子进程是否为进程组组长，可以向整个进程组发送信号
+func ownsProcessGroup(mode string) bool {
+	return mode != ProcessGroupInherit
+}
+
+// signalProcess group 为 true 时向进程组发送信号，否则只发给进程本身。
+// 留在 keeper 进程组中的子进程不能按组发送，否则 keeper 自己也会收到
+func signalProcess(pid int, sig syscall.Signal, group bool) error {
+	if group {
+		return signalProcessGroup(pid, sig)
+	}
+	return syscall.Kill(pid, sig)
+}
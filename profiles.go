@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// profileEnvVar 未通过 --profile 指定时读取的环境变量
+const profileEnvVar = "KEEPER_PROFILE"
+
+// ProfileConfig 环境相关的配置覆盖，通过 --profile 选择
+type ProfileConfig struct {
+	Processes []map[string]interface{} `json:"processes" yaml:"processes"` // 按 name 覆盖基础进程配置中出现的字段，environment 按键合并（值为 null 表示删除），name 不存在时新增进程
+}
+
+// applyProfile 把选中 profile 的进程配置合并到基础进程列表，profile 为空时不做任何修改
+func applyProfile(config *Config, profile string) error {
+	if profile == "" {
+		return nil
+	}
+	selected, exists := config.Profiles[profile]
+	if !exists {
+		return fmt.Errorf("配置中没有 profile: %s", profile)
+	}
+
+	index := make(map[string]int, len(config.Processes))
+	for i, processConfig := range config.Processes {
+		index[processConfig.Name] = i
+	}
+
+	for i, override := range selected.Processes {
+		name, _ := override["name"].(string)
+		if name == "" {
+			return fmt.Errorf("profile %s 的第 %d 个进程缺少 name", profile, i+1)
+		}
+
+		var base ProcessConfig
+		position, exists := index[name]
+		if exists {
+			base = config.Processes[position]
+		}
+		merged, err := mergeProcessConfig(base, override)
+		if err != nil {
+			return fmt.Errorf("profile %s 的进程 %s 无效: %v", profile, name, err)
+		}
+
+		if exists {
+			config.Processes[position] = merged
+		} else {
+			index[name] = len(config.Processes)
+			config.Processes = append(config.Processes, merged)
+		}
+	}
+	return nil
+}
+
+// mergeProcessConfig 用 override 中出现的字段覆盖 base，字段名与配置文件相同，未知字段报错
+func mergeProcessConfig(base ProcessConfig, override map[string]interface{}) (ProcessConfig, error) {
+	data, err := json.Marshal(base)
+	if err != nil {
+		return base, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return base, err
+	}
+
+	for key, value := range override {
+		if key != "environment" {
+			fields[key] = value
+			continue
+		}
+
+		extra, ok := value.(map[string]interface{})
+		if !ok {
+			return base, fmt.Errorf("environment 必须是键值对")
+		}
+		environment, _ := fields["environment"].(map[string]interface{})
+		if environment == nil {
+			environment = make(map[string]interface{})
+		}
+		for name, value := range extra {
+			switch value.(type) {
+			case nil:
+				delete(environment, name)
+			case string:
+				environment[name] = value
+			default:
+				environment[name] = fmt.Sprint(value)
+			}
+		}
+		fields["environment"] = environment
+	}
+
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return base, err
+	}
+	var merged ProcessConfig
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&merged); err != nil {
+		return base, err
+	}
+	return merged, nil
+}
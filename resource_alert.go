@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	resourceSampleInterval = 5 * time.Second  // 配置了 alert 的进程采样 CPU 和内存的间隔
+	defaultAlertDuration   = 60 * time.Second // 未配置 alert.duration 时持续超过阈值多久才告警
+	clockTicks             = 100              // /proc/<pid>/stat 中 CPU 时间的单位（USER_HZ），Linux 上固定为 100
+)
+
+// ResourceAlert 进程 CPU、内存占用的告警阈值，持续超过 duration 秒后告警，短暂的峰值不告警
+type ResourceAlert struct {
+	CPUPercent  float64 `json:"cpu_percent" yaml:"cpu_percent"`   // CPU 占用百分比阈值（多核时可超过 100），0 表示不检查
+	MemoryBytes int64   `json:"memory_bytes" yaml:"memory_bytes"` // 常驻内存（RSS）字节数阈值，0 表示不检查
+	Duration    int     `json:"duration" yaml:"duration"`         // 持续超过阈值多少秒后告警，默认 60
+}
+
+// enabled 判断是否配置了任一阈值
+func (a ResourceAlert) enabled() bool {
+	return a.CPUPercent > 0 || a.MemoryBytes > 0
+}
+
+// duration 返回持续超过阈值多久才告警
+func (a ResourceAlert) duration() time.Duration {
+	if a.Duration > 0 {
+		return time.Duration(a.Duration) * time.Second
+	}
+	return defaultAlertDuration
+}
+
+// validateResourceAlert 校验 alert
+func validateResourceAlert(alert ResourceAlert) error {
+	if alert.CPUPercent < 0 {
+		return fmt.Errorf("alert.cpu_percent 不能为负数")
+	}
+	if alert.MemoryBytes < 0 {
+		return fmt.Errorf("alert.memory_bytes 不能为负数")
+	}
+	if alert.Duration < 0 {
+		return fmt.Errorf("alert.duration 不能为负数")
+	}
+	return nil
+}
+
+// procStat /proc/<pid>/stat 中资源采样用到的字段
+type procStat struct {
+	pgrp  int    // 进程组 ID
+	ticks uint64 // 累计使用的 CPU 时间（USER_HZ），不包括子进程
+	rss   int64  // 常驻内存字节数
+}
+
+// readProcStat 读取并解析 /proc/<pid>/stat
+func readProcStat(pid int) (procStat, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return procStat{}, err
+	}
+	// 进程名（第 2 个字段）可能包含空格和括号，从最后一个右括号之后开始按空格拆分，第一个字段为 state（第 3 个字段）
+	stat := string(data)
+	end := strings.LastIndexByte(stat, ')')
+	if end < 0 {
+		return procStat{}, fmt.Errorf("无法解析 /proc/%d/stat", pid)
+	}
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 22 {
+		return procStat{}, fmt.Errorf("无法解析 /proc/%d/stat", pid)
+	}
+	pgrp, err1 := strconv.Atoi(fields[2])
+	utime, err2 := strconv.ParseUint(fields[11], 10, 64)
+	stime, err3 := strconv.ParseUint(fields[12], 10, 64)
+	pages, err4 := strconv.ParseInt(fields[21], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return procStat{}, fmt.Errorf("无法解析 /proc/%d/stat", pid)
+	}
+	return procStat{pgrp: pgrp, ticks: utime + stime, rss: pages * int64(os.Getpagesize())}, nil
+}
+
+// groupUsage 汇总 pid 所在进程组中所有进程的资源占用，返回各进程累计使用的 CPU 时间和常驻内存字节数之和。
+// shell: true 或派生工作进程的程序，实际占用资源的是组内的子进程；自行调用 setsid 离开进程组的进程不计入
+func groupUsage(pid int) (ticks map[int]uint64, rss int64, err error) {
+	leader, err := readProcStat(pid)
+	if err != nil {
+		return nil, 0, err
+	}
+	ticks = map[int]uint64{pid: leader.ticks}
+	rss = leader.rss
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return ticks, rss, nil
+	}
+	for _, entry := range entries {
+		other, err := strconv.Atoi(entry.Name())
+		if err != nil || other == pid {
+			continue
+		}
+		// 扫描期间退出的进程读取失败，直接跳过
+		stat, err := readProcStat(other)
+		if err != nil || stat.pgrp != leader.pgrp {
+			continue
+		}
+		ticks[other] = stat.ticks
+		rss += stat.rss
+	}
+	return ticks, rss, nil
+}
+
+// sampleUsage 采样进程的资源占用：进程是独立进程组的组长时汇总整个进程组，
+// process_group: inherit 时进程留在 keeper 的进程组中，只采样进程本身，避免计入 keeper 和其他进程
+func sampleUsage(pid int, group bool) (ticks map[int]uint64, rss int64, err error) {
+	if group {
+		return groupUsage(pid)
+	}
+	stat, err := readProcStat(pid)
+	if err != nil {
+		return nil, 0, err
+	}
+	return map[int]uint64{pid: stat.ticks}, stat.rss, nil
+}
+
+// usedTicks 返回两次采样之间进程组使用的 CPU 时间：已有的进程按差值计算，新出现的进程（或 PID 被复用）计入全部时间，
+// 已退出的进程不再计入，不会因为总和变小而得到负值
+func usedTicks(last, current map[int]uint64) uint64 {
+	var used uint64
+	for pid, ticks := range current {
+		if previous, ok := last[pid]; ok && ticks >= previous {
+			used += ticks - previous
+		} else {
+			used += ticks
+		}
+	}
+	return used
+}
+
+// exceeded 返回超过阈值的项目说明，未超过时返回空字符串
+func (a ResourceAlert) exceeded(cpu float64, rss int64) string {
+	var reasons []string
+	if a.CPUPercent > 0 && cpu > a.CPUPercent {
+		reasons = append(reasons, fmt.Sprintf("CPU %.1f%% 超过 %g%%", cpu, a.CPUPercent))
+	}
+	if a.MemoryBytes > 0 && rss > a.MemoryBytes {
+		reasons = append(reasons, fmt.Sprintf("内存 %d 字节超过 %d 字节", rss, a.MemoryBytes))
+	}
+	return strings.Join(reasons, "，")
+}
+
+// monitorResources 定期采样进程（独立进程组时为整个进程组）的 CPU 和内存占用，持续超过 alert 阈值时标记 ResourceAlert 并执行 alert_command，
+// 恢复正常后清除标记。进程退出或被替换后结束
+func (pm *ProcessManager) monitorResources(name string, procInfo *ProcessInfo, config ProcessConfig) {
+	alert := config.Alert
+	group := ownsProcessGroup(config.ProcessGroup)
+	ticker := time.NewTicker(resourceSampleInterval)
+	defer ticker.Stop()
+
+	var (
+		lastTicks  map[int]uint64
+		lastSample time.Time
+		overSince  time.Time
+	)
+
+	for {
+		select {
+		case <-procInfo.Done:
+			pm.mutex.Lock()
+			// 进程退出后 commands 中的记录可能已被删除，已被新的运行替换时不清除
+			current, running := pm.commands[name]
+			if status, exists := pm.processes[name]; exists && (!running || current == procInfo) {
+				status.CPUPercent = 0
+				status.MemoryBytes = 0
+				status.ResourceAlert = ""
+			}
+			pm.mutex.Unlock()
+			return
+		case <-ticker.C:
+		}
+
+		pm.mutex.Lock()
+		status, exists := pm.processes[name]
+		if !exists || pm.commands[name] != procInfo {
+			pm.mutex.Unlock()
+			return
+		}
+		pid := status.PID // forking 类型为守护进程的 PID
+		pm.mutex.Unlock()
+		if pid == 0 {
+			continue
+		}
+
+		ticks, rss, err := sampleUsage(pid, group)
+		now := time.Now()
+		if err != nil {
+			lastSample = time.Time{}
+			continue
+		}
+		// 第一次采样只记录基准，CPU 占用按两次采样之间的差值计算
+		first := lastSample.IsZero()
+		cpu := 0.0
+		if !first {
+			cpu = float64(usedTicks(lastTicks, ticks)) / clockTicks / now.Sub(lastSample).Seconds() * 100
+		}
+		since := lastSample
+		lastTicks, lastSample = ticks, now
+		if first {
+			continue
+		}
+
+		// CPU 占用是整个采样间隔的平均值，超过阈值的时间从间隔开始算起
+		reason := alert.exceeded(cpu, rss)
+		if reason == "" {
+			overSince = time.Time{}
+		} else if overSince.IsZero() {
+			overSince = since
+		}
+
+		pm.mutex.Lock()
+		if pm.commands[name] != procInfo {
+			pm.mutex.Unlock()
+			return
+		}
+		status.CPUPercent = cpu
+		status.MemoryBytes = rss
+		switch {
+		case reason == "" && status.ResourceAlert != "":
+			status.ResourceAlert = ""
+			pm.addLog(name, "INFO: 资源占用已恢复到告警阈值以下")
+			logInfof("进程 %s 资源占用已恢复到告警阈值以下", name)
+		case reason != "" && status.ResourceAlert == "" && now.Sub(overSince) >= alert.duration():
+			status.ResourceAlert = reason
+			pm.addLog(name, fmt.Sprintf("ERROR: 资源占用持续 %g 秒超过告警阈值：%s", alert.duration().Seconds(), reason))
+			logErrorf("告警: 进程 %s 资源占用持续超过阈值：%s", name, reason)
+			if status.Config.AlertCommand != "" {
+				go pm.runAlertCommand(name, copyProcessConfig(status.Config), map[string]string{
+					"KEEPER_ALERT":        "resource",
+					"KEEPER_ALERT_REASON": reason,
+					"KEEPER_CPU_PERCENT":  fmt.Sprintf("%.1f", cpu),
+					"KEEPER_MEMORY_BYTES": fmt.Sprint(rss),
+				})
+			}
+		}
+		pm.mutex.Unlock()
+	}
+}
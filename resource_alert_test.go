@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+)
+
+func TestGroupUsageIncludesChildren(t *testing.T) {
+	// shell 本身几乎不占资源，实际工作在子进程中
+	cmd := exec.Command("sh", "-c", "sleep 30 & sleep 30 & wait")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		cmd.Wait()
+	})
+
+	waitFor(t, "统计到进程组中的子进程", func() bool {
+		ticks, _, err := groupUsage(cmd.Process.Pid)
+		return err == nil && len(ticks) == 3
+	})
+	_, rss, err := groupUsage(cmd.Process.Pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leader, err := readProcStat(cmd.Process.Pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rss <= leader.rss {
+		t.Fatalf("进程组内存 %d 没有包含子进程（shell 为 %d）", rss, leader.rss)
+	}
+}
+
+func TestUsedTicksAcrossExitedAndNewProcesses(t *testing.T) {
+	last := map[int]uint64{1: 100, 2: 50, 3: 80}
+	// 2 退出，4 为新进程，3 的 PID 被复用
+	current := map[int]uint64{1: 130, 3: 5, 4: 20}
+	if used := usedTicks(last, current); used != 30+5+20 {
+		t.Fatalf("使用的 CPU 时间为 %d，期望 55", used)
+	}
+}
+
+func TestSampleUsageInheritGroupOnlyCountsProcess(t *testing.T) {
+	// process_group: inherit 的子进程与 keeper（这里是测试进程）在同一个进程组中
+	cmd := exec.Command("sleep", "30")
+	cmd.SysProcAttr = processSysProcAttr(ProcessGroupInherit)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	waitFor(t, "子进程执行 sleep", func() bool {
+		comm, _ := os.ReadFile(fmt.Sprintf("/proc/%d/comm", cmd.Process.Pid))
+		return string(comm) == "sleep\n"
+	})
+
+	ticks, rss, err := sampleUsage(cmd.Process.Pid, ownsProcessGroup(ProcessGroupInherit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat, err := readProcStat(cmd.Process.Pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ticks) != 1 || rss != stat.rss {
+		t.Fatalf("采样了 %d 个进程，内存 %d，期望只有子进程本身（%d）", len(ticks), rss, stat.rss)
+	}
+	if keeper, err := readProcStat(os.Getpid()); err != nil || keeper.pgrp != stat.pgrp {
+		t.Fatalf("子进程没有留在测试进程的进程组中: %v", err)
+	}
+}
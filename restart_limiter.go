@@ -0,0 +1,83 @@
+package main
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// jitterDelay 在 delay 上加 ±jitter 比例的随机浮动，精确到毫秒，jitter <= 0 时原样返回
+func jitterDelay(delay time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 {
+		return delay
+	}
+	factor := 1 + jitter*(2*rand.Float64()-1)
+	return time.Duration(float64(delay) * factor).Round(time.Millisecond)
+}
+
+// restartLimiter 全局自动重启限流器，限制同时进行的重启数量和重启之间的最小间隔
+type restartLimiter struct {
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	inFlight int
+	last     time.Time
+}
+
+// newRestartLimiter 创建重启限流器
+func newRestartLimiter() *restartLimiter {
+	rl := &restartLimiter{}
+	rl.cond = sync.NewCond(&rl.mutex)
+	return rl
+}
+
+// acquire 等待获取重启名额，需要等待时调用 onWait（每次获取最多调用一次）
+// maxConcurrent <= 0 表示不限制并发，interval <= 0 表示不限制间隔。
+// onWait 在释放 rl.mutex 后调用，可以获取 pm.mutex，不会与持有 pm.mutex 再调用 release 的一方死锁
+func (rl *restartLimiter) acquire(maxConcurrent int, interval time.Duration, onWait func(reason string)) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	notified := false
+	// notify 首次需要等待时释放锁调用 onWait，返回 true 表示调用过，调用方需重新检查条件
+	notify := func(reason string) bool {
+		if notified || onWait == nil {
+			return false
+		}
+		notified = true
+		rl.mutex.Unlock()
+		onWait(reason)
+		rl.mutex.Lock()
+		return true
+	}
+
+	for {
+		if maxConcurrent > 0 && rl.inFlight >= maxConcurrent {
+			if !notify("同时进行的重启数量已达上限") {
+				rl.cond.Wait()
+			}
+			continue
+		}
+
+		if wait := interval - time.Since(rl.last); interval > 0 && wait > 0 {
+			if !notify("距离上一次重启间隔过短") {
+				rl.mutex.Unlock()
+				time.Sleep(wait)
+				rl.mutex.Lock()
+			}
+			continue
+		}
+
+		break
+	}
+
+	rl.inFlight++
+	rl.last = time.Now()
+}
+
+// release 释放重启名额
+func (rl *restartLimiter) release() {
+	rl.mutex.Lock()
+	rl.inFlight--
+	rl.mutex.Unlock()
+	rl.cond.Signal()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRestartLimiterNotifiesWithoutLock(t *testing.T) {
+	rl := newRestartLimiter()
+	rl.acquire(1, 0, nil)
+
+	acquired := make(chan struct{})
+	go func() {
+		rl.acquire(1, 0, func(string) {
+			// onWait 可能获取 pm.mutex，不能在持有 rl.mutex 时调用
+			if !rl.mutex.TryLock() {
+				t.Error("调用 onWait 时仍持有 rl.mutex")
+				return
+			}
+			rl.mutex.Unlock()
+			rl.release()
+		})
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("释放名额后仍未获取到")
+	}
+}
+
+func TestAwaitStartupWaitsForGrace(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: svc
+    command: fake-sleep
+    enabled: true
+    startup_grace: 1
+`)
+
+	if err := pm.StartProcess("svc"); err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+	// 自动重启占用名额直到启动保护期结束
+	pm.awaitStartup("svc")
+	if status := processState(t, pm, "svc"); status.Status != "running" {
+		t.Fatalf("等待结束时状态为 %s", status.Status)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+)
+
+// Runner 创建子进程命令，ProcessManager 启动进程和执行钩子时都通过它进行，
+// 测试时可以替换为返回假命令（如测试辅助进程）的实现，不依赖真实的可执行文件
+type Runner interface {
+	CommandContext(ctx context.Context, name string, args ...string) *exec.Cmd
+	LookPath(file string) (string, error)
+}
+
+// execRunner 默认的 Runner，直接使用 os/exec
+type execRunner struct{}
+
+func (execRunner) CommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, name, args...)
+}
+
+func (execRunner) LookPath(file string) (string, error) {
+	return exec.LookPath(file)
+}
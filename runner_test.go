@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// helperFlag 测试二进制以该参数启动时作为假命令运行，而不是执行测试
+const helperFlag = "-keeper-test-helper"
+
+// helperCommands fakeRunner 模拟的命令，其他命令仍交给 os/exec 执行（如钩子使用的 /bin/sh）
+var helperCommands = []string{"fake-sleep", "fake-exit", "fake-echo", "fake-args", "fake-kill"}
+
+func TestMain(m *testing.M) {
+	if len(os.Args) > 2 && os.Args[1] == helperFlag {
+		os.Exit(runHelper(os.Args[2], os.Args[3:]))
+	}
+	os.Exit(m.Run())
+}
+
+// runHelper 假命令的行为：
+// fake-sleep 一直运行直到被停止；fake-exit N 以退出码 N 退出；fake-echo 输出参数；
+// fake-args 每行输出一个带引号的参数；fake-kill N 向自身发送信号 N
+func runHelper(name string, args []string) int {
+	switch name {
+	case "fake-sleep":
+		time.Sleep(time.Hour)
+	case "fake-exit":
+		code, _ := strconv.Atoi(args[0])
+		return code
+	case "fake-echo":
+		fmt.Println(strings.Join(args, " "))
+	case "fake-args":
+		for _, arg := range args {
+			fmt.Printf("%q\n", arg)
+		}
+	case "fake-kill":
+		sig, _ := strconv.Atoi(args[0])
+		syscall.Kill(os.Getpid(), syscall.Signal(sig))
+		time.Sleep(time.Hour)
+	}
+	return 0
+}
+
+// fakeRunner 测试用的 Runner：helperCommands 中的命令改为启动测试二进制本身模拟，不依赖真实的可执行文件，
+// 并记录每次创建的命令，用于断言启动了几次、参数是什么
+type fakeRunner struct {
+	mutex sync.Mutex
+	calls [][]string // 每次 CommandContext 的命令名称和参数
+}
+
+func (r *fakeRunner) CommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	if !slices.Contains(helperCommands, name) {
+		return exec.CommandContext(ctx, name, args...)
+	}
+	r.mutex.Lock()
+	r.calls = append(r.calls, append([]string{name}, args...))
+	r.mutex.Unlock()
+	return exec.CommandContext(ctx, os.Args[0], append([]string{helperFlag, name}, args...)...)
+}
+
+func (r *fakeRunner) LookPath(file string) (string, error) {
+	if slices.Contains(helperCommands, file) {
+		return "/fake/bin/" + file, nil
+	}
+	return exec.LookPath(file)
+}
+
+// Calls 返回已创建的假命令
+func (r *fakeRunner) Calls() [][]string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return slices.Clone(r.calls)
+}
+
+// writeTestConfig 写入配置文件，并把修改时间推后，保证重新加载时能识别出变化
+func writeTestConfig(t *testing.T, path, config string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Duration(len(config)) * time.Millisecond)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestManager 用 fakeRunner 创建进程管理器并加载配置，测试结束时停止所有仍在运行的进程
+func newTestManager(t *testing.T, config string) (*ProcessManager, *fakeRunner) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keeper.yaml")
+	writeTestConfig(t, path, config)
+
+	pm := NewProcessManager(path, ServerOverrides{})
+	runner := &fakeRunner{}
+	pm.runner = runner
+	if err := pm.LoadConfig(); err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	t.Cleanup(func() {
+		for name, status := range pm.GetProcesses() {
+			if status.Status == "running" || status.Status == "starting" || status.Status == "paused" {
+				pm.StopProcess(name)
+			}
+		}
+	})
+	return pm, runner
+}
+
+// waitFor 等待条件成立，超时则测试失败
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待超时: %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// processState 返回进程状态的副本，进程不存在时测试失败
+func processState(t *testing.T, pm *ProcessManager, name string) *ProcessStatus {
+	t.Helper()
+	status, exists := pm.GetProcess(name)
+	if !exists {
+		t.Fatalf("进程 %s 不存在", name)
+	}
+	return status
+}
+
+func TestStartAndStopWithFakeRunner(t *testing.T) {
+	pm, runner := newTestManager(t, `
+processes:
+  - name: svc
+    command: fake-sleep
+    args: ["--port", "80"]
+    enabled: true
+`)
+
+	if err := pm.StartProcess("svc"); err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+	status := processState(t, pm, "svc")
+	if status.Status != "running" || status.PID == 0 {
+		t.Fatalf("启动后状态为 %s，PID %d", status.Status, status.PID)
+	}
+	if calls := runner.Calls(); len(calls) != 1 || !slices.Equal(calls[0], []string{"fake-sleep", "--port", "80"}) {
+		t.Fatalf("创建的命令为 %q", calls)
+	}
+
+	if err := pm.StopProcess("svc"); err != nil {
+		t.Fatalf("StopProcess: %v", err)
+	}
+	status = processState(t, pm, "svc")
+	if status.Status != "stopped" || status.StopReason != StopReasonManual || status.PID != 0 {
+		t.Fatalf("停止后状态为 %s（%s），PID %d", status.Status, status.StopReason, status.PID)
+	}
+}
+
+func TestMonitorRecordsCrash(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: job
+    command: fake-exit
+    args: ["3"]
+    enabled: true
+`)
+
+	if err := pm.StartProcess("job"); err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+	waitFor(t, "进程退出", func() bool { return processState(t, pm, "job").Status == "stopped" })
+
+	status := processState(t, pm, "job")
+	if status.LastExitCode != 3 || status.StopReason != StopReasonCrashed {
+		t.Fatalf("退出码 %d，停止原因 %s", status.LastExitCode, status.StopReason)
+	}
+	if status.Restarts != 1 {
+		t.Fatalf("重启计数为 %d，期望 1", status.Restarts)
+	}
+}
+
+func TestAutoRestartUntilDisabled(t *testing.T) {
+	pm, runner := newTestManager(t, `
+processes:
+  - name: flappy
+    command: fake-exit
+    args: ["1"]
+    enabled: true
+    auto_restart: true
+    max_restarts: 2
+    restart_delay: 1
+`)
+
+	if err := pm.StartProcess("flappy"); err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+	waitFor(t, "达到重启上限后禁用", func() bool { return processState(t, pm, "flappy").Status == "disabled" })
+
+	status := processState(t, pm, "flappy")
+	if status.Restarts != 2 || status.Config.AutoRestart {
+		t.Fatalf("重启计数 %d，auto_restart %v", status.Restarts, status.Config.AutoRestart)
+	}
+	if n := len(runner.Calls()); n != 2 {
+		t.Fatalf("启动了 %d 次，期望 2 次（首次启动和一次自动重启）", n)
+	}
+}
+
+func TestCheckExecutableUsesRunner(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: fake
+    command: fake-sleep
+  - name: missing
+    command: keeper-test-no-such-command
+`)
+
+	// fake-sleep 不是真实的可执行文件，只有通过 runner 查找才能找到
+	if status := processState(t, pm, "fake"); !status.Preflight.OK() {
+		t.Fatalf("fake-sleep 的启动前检查未通过: %s", status.Preflight.Note)
+	}
+	if status := processState(t, pm, "missing"); status.Preflight.ExecutableFound {
+		t.Fatal("不存在的命令通过了启动前检查")
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// schemaGenerator 根据结构体的 json 标签生成 JSON Schema，具名结构体放入 defs 并通过 $ref 引用
+type schemaGenerator struct {
+	refPrefix string // $ref 前缀，JSON Schema 为 #/$defs/，OpenAPI 为 #/components/schemas/
+	defs      map[string]interface{}
+}
+
+// newSchemaGenerator 创建 schema 生成器
+func newSchemaGenerator(refPrefix string) *schemaGenerator {
+	return &schemaGenerator{refPrefix: refPrefix, defs: make(map[string]interface{})}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema 返回类型对应的 schema，具名结构体返回 $ref
+func (g *schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		if _, exists := g.defs[t.Name()]; !exists {
+			g.defs[t.Name()] = nil // 先占位，避免结构体互相引用时无限递归
+			g.defs[t.Name()] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": g.refPrefix + t.Name()}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		return g.structSchema(t)
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// structSchema 按 json 标签生成结构体的 object schema，跳过未导出字段和标签为 - 的字段
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.schema(field.Type)
+	}
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+// configSchema 生成配置文件（Config）的 JSON Schema
+func configSchema() map[string]interface{} {
+	g := newSchemaGenerator("#/$defs/")
+	schema := g.structSchema(reflect.TypeOf(Config{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "keeper 配置"
+	schema["$defs"] = g.defs
+	return schema
+}
+
+// apiEndpoint OpenAPI 文档中的一个接口
+type apiEndpoint struct {
+	method   string
+	path     string
+	summary  string
+	query    []map[string]interface{} // 查询参数，路径参数根据 path 自动生成
+	body     map[string]interface{}   // 请求体的 content，为空表示没有请求体
+	response map[string]interface{}   // 成功响应的 content
+}
+
+// queryParam 创建查询参数
+func queryParam(name, typ, description string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        name,
+		"in":          "query",
+		"description": description,
+		"schema":      map[string]interface{}{"type": typ},
+	}
+}
+
+// jsonContent 创建 application/json 的 content
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// objectSchema 创建具有指定属性的 object schema
+func objectSchema(properties map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// openAPIDocument 生成 HTTP API 的 OpenAPI 3.1 文档，数据结构的 schema 由结构体的 json 标签生成
+func openAPIDocument() map[string]interface{} {
+	g := newSchemaGenerator("#/components/schemas/")
+	ref := func(v interface{}) map[string]interface{} { return g.schema(reflect.TypeOf(v)) }
+	str := map[string]interface{}{"type": "string"}
+	boolean := map[string]interface{}{"type": "boolean"}
+	integer := map[string]interface{}{"type": "integer"}
+	array := func(items map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"type": "array", "items": items}
+	}
+	dict := func(values map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"type": "object", "additionalProperties": values}
+	}
+
+	message := jsonContent(objectSchema(map[string]interface{}{"success": boolean, "message": str}))
+	health := jsonContent(objectSchema(map[string]interface{}{"status": str, "error": str}))
+	bulk := jsonContent(objectSchema(map[string]interface{}{"success": boolean, "results": dict(ref(BulkResult{}))}))
+	actions := []string{"start", "stop", "restart", "pause", "resume", "recover", "reset-restarts"}
+
+	endpoints := []apiEndpoint{
+		{method: "get", path: "/api/process/{name}/status", summary: "获取单个进程状态",
+			query:    []map[string]interface{}{queryParam("env", "boolean", "为 true 时附带启动时的环境变量，敏感值已脱敏")},
+			response: jsonContent(ref(ProcessStatus{}))},
+		{method: "get", path: "/api/process/{name}/tail", summary: "以 Server-Sent Events 实时推送进程日志，先发送缓冲中已有的行",
+			query:    []map[string]interface{}{queryParam("lines", "integer", "只先发送缓冲中最近的行数，0 表示全部")},
+			response: map[string]interface{}{"text/event-stream": map[string]interface{}{"schema": str}}},
+		{method: "post", path: "/api/process/{name}/{action}", summary: "启动、停止、重启、暂停、恢复进程，recover 为重新启用并启动，reset-restarts 只重置重启计数",
+			query:    []map[string]interface{}{queryParam("force", "boolean", "action 为 start 时强制启动未启用的进程")},
+			response: message},
+		{method: "post", path: "/api/process/{name}/signal", summary: "向进程发送信号",
+			query: []map[string]interface{}{
+				queryParam("signal", "string", "信号名称或编号，也可以通过请求体指定"),
+				queryParam("group", "boolean", "为 false 时只发送给进程本身，默认发送给整个进程组"),
+			},
+			body:     jsonContent(objectSchema(map[string]interface{}{"signal": str})),
+			response: message},
+		{method: "post", path: "/api/process/{name}/stdin", summary: "向启用了 stdin_open 的进程写入标准输入",
+			body:     map[string]interface{}{"application/octet-stream": map[string]interface{}{"schema": map[string]interface{}{"type": "string", "format": "binary"}}},
+			response: message},
+		{method: "post", path: "/api/all/{action}", summary: "对所有进程执行操作", response: bulk},
+		{method: "post", path: "/api/group/{tag}/{action}", summary: "对带有指定标签的进程执行操作", response: bulk},
+		{method: "post", path: "/api/enable/{name}", summary: "重新启用因重启次数过多被禁用的进程", response: message},
+		{method: "post", path: "/api/reload", summary: "重新加载配置", response: message},
+		{method: "post", path: "/api/recover", summary: "重新启用并启动所有出错或被禁用的进程", response: bulk},
+		{method: "get", path: "/api/logs/search", summary: "搜索所有进程的日志",
+			query: []map[string]interface{}{
+				queryParam("q", "string", "搜索内容"),
+				queryParam("regex", "boolean", "为 true 时按正则表达式匹配"),
+				queryParam("limit", "integer", "最多返回的匹配行数"),
+			},
+			response: jsonContent(objectSchema(map[string]interface{}{
+				"success":   boolean,
+				"results":   dict(array(ref(logMatch{}))),
+				"total":     integer,
+				"truncated": boolean,
+			}))},
+		{method: "get", path: "/api/logs/{name}", summary: "获取进程日志",
+			response: jsonContent(objectSchema(map[string]interface{}{
+				"success":      boolean,
+				"logs":         array(str),
+				"highlight":    array(boolean),
+				"command_line": array(str),
+			}))},
+		{method: "get", path: "/api/logs/{name}/download", summary: "下载进程日志",
+			response: map[string]interface{}{"text/plain": map[string]interface{}{"schema": str}}},
+		{method: "delete", path: "/api/logs/{name}", summary: "清空进程日志", response: message},
+		{method: "get", path: "/api/keeper/logs", summary: "获取 keeper 自身最近的日志",
+			query:    []map[string]interface{}{queryParam("lines", "integer", "只返回最近的行数，0 表示全部")},
+			response: jsonContent(objectSchema(map[string]interface{}{"success": boolean, "logs": array(str)}))},
+		{method: "get", path: "/api/status", summary: "获取所有进程状态，按 order 和名称排序", response: jsonContent(array(ref(ProcessStatus{})))},
+		{method: "get", path: "/api/processes", summary: "分页获取进程列表",
+			query: []map[string]interface{}{
+				queryParam("offset", "integer", "跳过的进程数"),
+				queryParam("limit", "integer", "返回的进程数，0 表示不限制"),
+				queryParam("status", "string", "按状态过滤"),
+				queryParam("tag", "string", "按标签过滤"),
+			},
+			response: jsonContent(objectSchema(map[string]interface{}{
+				"success":   boolean,
+				"total":     integer,
+				"offset":    integer,
+				"limit":     integer,
+				"processes": array(ref(ProcessStatus{})),
+			}))},
+		{method: "get", path: "/api/summary", summary: "获取进程汇总统计", response: jsonContent(ref(ProcessSummary{}))},
+		{method: "get", path: "/api/preflight", summary: "重新执行启动前检查",
+			response: jsonContent(objectSchema(map[string]interface{}{
+				"success": boolean,
+				"results": dict(ref(PreflightResult{})),
+				"failed":  integer,
+			}))},
+		{method: "get", path: "/api/config", summary: "获取当前配置，敏感值已脱敏",
+			response: jsonContent(objectSchema(map[string]interface{}{"success": boolean, "config": ref(Config{}), "last_reload": ref(ReloadResult{})}))},
+		{method: "get", path: "/api/version", summary: "获取版本信息", response: jsonContent(ref(BuildInfo{}))},
+		{method: "get", path: "/api/schema", summary: "获取配置文件的 JSON Schema", response: jsonContent(map[string]interface{}{"type": "object"})},
+		{method: "get", path: "/api/openapi.json", summary: "获取本文档", response: jsonContent(map[string]interface{}{"type": "object"})},
+		{method: "get", path: "/healthz", summary: "存活检查", response: health},
+		{method: "get", path: "/readyz", summary: "就绪检查", response: health},
+	}
+
+	errorResponse := map[string]interface{}{
+		"description": "失败",
+		"content": jsonContent(objectSchema(map[string]interface{}{
+			"success": boolean,
+			"error":   str,
+			"logs":    array(str), // 只在启动失败时返回
+		})),
+	}
+
+	paths := make(map[string]interface{})
+	for _, endpoint := range endpoints {
+		var parameters []map[string]interface{}
+		for _, match := range pathParamPattern.FindAllStringSubmatch(endpoint.path, -1) {
+			schema := map[string]interface{}{"type": "string"}
+			if match[1] == "action" {
+				schema["enum"] = actions
+			}
+			parameters = append(parameters, map[string]interface{}{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   schema,
+			})
+		}
+		parameters = append(parameters, endpoint.query...)
+
+		operation := map[string]interface{}{
+			"summary": endpoint.summary,
+			"responses": map[string]interface{}{
+				"200":     map[string]interface{}{"description": "成功", "content": endpoint.response},
+				"default": errorResponse,
+			},
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		if endpoint.body != nil {
+			operation["requestBody"] = map[string]interface{}{"content": endpoint.body}
+		}
+
+		item, _ := paths[endpoint.path].(map[string]interface{})
+		if item == nil {
+			item = make(map[string]interface{})
+			paths[endpoint.path] = item
+		}
+		item[endpoint.method] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.1.0",
+		"info": map[string]interface{}{
+			"title":   "keeper API",
+			"version": Version,
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": g.defs},
+	}
+}
+
+// 配置文件 JSON Schema API
+func (pm *ProcessManager) handleSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	json.NewEncoder(w).Encode(configSchema())
+}
+
+// OpenAPI 文档 API
+func (pm *ProcessManager) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(openAPIDocument())
+}
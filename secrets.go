@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+)
+
+// defaultSecretKeys 未配置 secret_keys 时，名称包含这些关键字的环境变量视为敏感信息
+var defaultSecretKeys = []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "KEY"}
+
+// redactedValue 敏感值的替换文本
+const redactedValue = "***"
+
+// effectiveSecretKeys 获取生效的敏感关键字，未配置时使用默认值
+func effectiveSecretKeys(server ServerConfig) []string {
+	if len(server.SecretKeys) > 0 {
+		return server.SecretKeys
+	}
+	return defaultSecretKeys
+}
+
+// isSecretKey 判断环境变量名称是否包含任一敏感关键字（不区分大小写）
+func isSecretKey(key string, secretKeys []string) bool {
+	upper := strings.ToUpper(key)
+	for _, secret := range secretKeys {
+		if secret != "" && strings.Contains(upper, strings.ToUpper(secret)) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactEnvironment 将 KEY=VALUE 形式的环境变量转换为映射，敏感变量的值被替换
+func redactEnvironment(env []string, secretKeys []string) map[string]string {
+	result := make(map[string]string, len(env))
+	for _, entry := range env {
+		key, value, _ := strings.Cut(entry, "=")
+		if isSecretKey(key, secretKeys) {
+			value = redactedValue
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// redactProcessConfig 返回敏感环境变量已被替换的进程配置副本
+func redactProcessConfig(config ProcessConfig, secretKeys []string) ProcessConfig {
+	if len(config.Environment) == 0 {
+		return config
+	}
+	environment := make(map[string]string, len(config.Environment))
+	for key, value := range config.Environment {
+		if isSecretKey(key, secretKeys) {
+			value = redactedValue
+		}
+		environment[key] = value
+	}
+	config.Environment = environment
+	return config
+}
+
+// redactConfig 返回所有进程敏感环境变量已被替换的配置副本
+func redactConfig(config *Config, secretKeys []string) *Config {
+	redacted := *config
+	redacted.Processes = make([]ProcessConfig, len(config.Processes))
+	for i, processConfig := range config.Processes {
+		redacted.Processes[i] = redactProcessConfig(processConfig, secretKeys)
+	}
+	return &redacted
+}
+
+// secretValues 获取进程配置中敏感环境变量的值，用于从输出中抹除
+func secretValues(environment map[string]string, secretKeys []string) []string {
+	var values []string
+	for key, value := range environment {
+		if value != "" && isSecretKey(key, secretKeys) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// scrubSecrets 将文本中出现的敏感值替换掉
+func scrubSecrets(line string, values []string) string {
+	for _, value := range values {
+		line = strings.ReplaceAll(line, value, redactedValue)
+	}
+	return line
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// serverDrainTimeout 重新绑定监听地址时，等待旧地址上的请求处理完成的最长时间
+const serverDrainTimeout = 10 * time.Second
+
+// 未配置 read_timeout、write_timeout、idle_timeout 时 Web 服务使用的超时时间
+const (
+	defaultReadTimeout  = 30 * time.Second
+	defaultWriteTimeout = 120 * time.Second
+	defaultIdleTimeout  = 120 * time.Second
+)
+
+// serverTimeouts Web 服务的连接超时时间，避免慢速客户端或挂起的连接长期占用资源
+type serverTimeouts struct {
+	read  time.Duration
+	write time.Duration
+	idle  time.Duration
+}
+
+// timeoutsFor 返回服务器配置中的超时时间，未配置（0）时使用默认值
+func timeoutsFor(server ServerConfig) serverTimeouts {
+	seconds := func(value int, fallback time.Duration) time.Duration {
+		if value > 0 {
+			return time.Duration(value) * time.Second
+		}
+		return fallback
+	}
+	return serverTimeouts{
+		read:  seconds(server.ReadTimeout, defaultReadTimeout),
+		write: seconds(server.WriteTimeout, defaultWriteTimeout),
+		idle:  seconds(server.IdleTimeout, defaultIdleTimeout),
+	}
+}
+
+// listenTarget 一个监听地址，network 为 tcp 或 unix
+type listenTarget struct {
+	network string
+	address string
+}
+
+// String 返回用于展示的地址
+func (t listenTarget) String() string {
+	if t.network == "unix" {
+		return "unix:" + t.address
+	}
+	return "http://" + t.address
+}
+
+// listenTargets 根据服务器配置返回要监听的地址：配置了 unix_socket 时只监听该套接字，否则为 TCP 地址
+func listenTargets(server ServerConfig) ([]listenTarget, error) {
+	if server.UnixSocket != "" {
+		return []listenTarget{{network: "unix", address: server.UnixSocket}}, nil
+	}
+
+	addresses, err := listenAddresses(server)
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]listenTarget, 0, len(addresses))
+	for _, address := range addresses {
+		targets = append(targets, listenTarget{network: "tcp", address: address})
+	}
+	return targets, nil
+}
+
+// serverListenChanged 判断两份服务器配置的监听地址是否不同
+func serverListenChanged(old, new ServerConfig) bool {
+	oldTargets, oldErr := listenTargets(old)
+	newTargets, newErr := listenTargets(new)
+	return oldErr != nil || newErr != nil || !slices.Equal(oldTargets, newTargets)
+}
+
+// listenOn 监听地址，Unix 套接字会先清理上次异常退出遗留的套接字文件
+func listenOn(target listenTarget) (net.Listener, error) {
+	if target.network == "unix" {
+		if info, err := os.Stat(target.address); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(target.address)
+		}
+	}
+	return net.Listen(target.network, target.address)
+}
+
+// webServer 在一个或多个地址上提供 Web 服务，所有地址使用同一个 handler。
+// 重新加载配置时按新的监听地址增减监听，不影响进程管理
+type webServer struct {
+	handler  http.Handler
+	mutex    sync.Mutex
+	servers  map[listenTarget]*http.Server
+	timeouts serverTimeouts // 新绑定的地址使用的超时时间，已在服务的地址保持绑定时的值
+	failed   chan struct{}  // 所有地址都因错误停止服务时关闭
+	once     sync.Once
+	latest   atomic.Uint64 // 最近一次 applyAsync 的序号，较早的后台重新绑定开始执行时已过时则跳过
+}
+
+// newWebServer 创建 Web 服务
+func newWebServer(handler http.Handler) *webServer {
+	return &webServer{
+		handler: handler,
+		servers: make(map[listenTarget]*http.Server),
+		failed:  make(chan struct{}),
+	}
+}
+
+// start 按服务器配置开始监听，部分地址监听失败只记录错误，全部失败才返回错误
+func (ws *webServer) start(server ServerConfig) error {
+	targets, err := listenTargets(server)
+	if err != nil {
+		return err
+	}
+
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+
+	ws.timeouts = timeoutsFor(server)
+	var errs []string
+	for _, target := range targets {
+		if err := ws.listenLocked(target); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(ws.servers) == 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// listenLocked 监听一个地址并开始服务，调用方需持有 ws.mutex
+func (ws *webServer) listenLocked(target listenTarget) error {
+	listener, err := listenOn(target)
+	if err != nil {
+		logErrorf("监听 %s 失败: %v", target.address, err)
+		return err
+	}
+
+	srv := &http.Server{
+		Handler:      ws.handler,
+		ReadTimeout:  ws.timeouts.read,
+		WriteTimeout: ws.timeouts.write,
+		IdleTimeout:  ws.timeouts.idle,
+	}
+	ws.servers[target] = srv
+	logInfof("Web界面: %s", target)
+
+	go func() {
+		err := srv.Serve(listener)
+		if errors.Is(err, http.ErrServerClosed) {
+			return // 重新绑定时主动关闭
+		}
+		logErrorf("监听地址 %s 停止服务: %v", target, err)
+
+		ws.mutex.Lock()
+		defer ws.mutex.Unlock()
+		if ws.servers[target] == srv {
+			delete(ws.servers, target)
+		}
+		if len(ws.servers) == 0 {
+			ws.once.Do(func() { close(ws.failed) })
+		}
+	}()
+	return nil
+}
+
+// shutdownLocked 停止一个地址的服务，等待进行中的请求完成，超时后强制关闭连接。调用方需持有 ws.mutex
+func (ws *webServer) shutdownLocked(target listenTarget) {
+	srv := ws.servers[target]
+	delete(ws.servers, target)
+
+	ctx, cancel := context.WithTimeout(context.Background(), serverDrainTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		logWarnf("等待监听地址 %s 上的请求完成超时，强制关闭: %v", target, err)
+		srv.Close()
+	}
+	if target.network == "unix" {
+		os.Remove(target.address)
+	}
+	logInfof("已停止监听 %s", target)
+}
+
+// applyAsync 在后台调用 apply，不等待旧地址上的请求完成。连续多次调用时最终生效的是最后一次的配置：
+// 协程开始的顺序不确定，轮到执行时已有更新的调用就跳过，不会用旧配置覆盖新配置
+func (ws *webServer) applyAsync(server ServerConfig) {
+	seq := ws.latest.Add(1)
+	go func() {
+		ws.mutex.Lock()
+		defer ws.mutex.Unlock()
+		if ws.latest.Load() != seq {
+			return
+		}
+		ws.applyLocked(server)
+	}()
+}
+
+// apply 按新的服务器配置重新绑定监听地址
+func (ws *webServer) apply(server ServerConfig) {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	ws.applyLocked(server)
+}
+
+// applyLocked 先监听新增的地址，再停止不再需要的地址（等待请求完成）。
+// 新地址与旧地址冲突（如 0.0.0.0:8080 改为 127.0.0.1:8080）时，在旧地址停止后重试；
+// 最终没有任何可用地址时恢复旧的监听，避免 Web 界面无法访问。调用方需持有 ws.mutex
+func (ws *webServer) applyLocked(server ServerConfig) {
+	targets, err := listenTargets(server)
+	if err != nil {
+		logErrorf("监听地址无效，保持当前监听: %v", err)
+		return
+	}
+
+	ws.timeouts = timeoutsFor(server)
+	var removed, retry []listenTarget
+	for target := range ws.servers {
+		if !slices.Contains(targets, target) {
+			removed = append(removed, target)
+		}
+	}
+	for _, target := range targets {
+		if _, exists := ws.servers[target]; exists {
+			continue
+		}
+		if err := ws.listenLocked(target); err != nil {
+			retry = append(retry, target)
+		}
+	}
+	if len(removed) == 0 && len(retry) == 0 {
+		return
+	}
+
+	logInfof("服务器监听地址已变化，重新绑定")
+	for _, target := range removed {
+		ws.shutdownLocked(target)
+	}
+	for _, target := range retry {
+		ws.listenLocked(target)
+	}
+
+	if len(ws.servers) == 0 {
+		logErrorf("新的监听地址均不可用，恢复原来的监听地址")
+		for _, target := range removed {
+			ws.listenLocked(target)
+		}
+	}
+}
+
+// removeSockets 删除监听的 Unix 套接字文件，keeper 退出时调用
+func (ws *webServer) removeSockets() {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+
+	for target := range ws.servers {
+		if target.network == "unix" {
+			os.Remove(target.address)
+		}
+	}
+}
+
+// wait 阻塞直到所有监听地址都因错误停止服务
+func (ws *webServer) wait() error {
+	<-ws.failed
+	return fmt.Errorf("所有监听地址均已停止服务")
+}
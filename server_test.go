@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// listening 返回 webServer 当前监听的地址
+func listening(ws *webServer) []string {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	var addresses []string
+	for target := range ws.servers {
+		addresses = append(addresses, target.address)
+	}
+	return addresses
+}
+
+func TestApplyAsyncKeepsLatestConfig(t *testing.T) {
+	dir := t.TempDir()
+	socket := func(name string) string { return filepath.Join(dir, name) }
+
+	ws := newWebServer(http.NotFoundHandler())
+	if err := ws.start(ServerConfig{UnixSocket: socket("a.sock")}); err != nil {
+		t.Fatal(err)
+	}
+	defer ws.apply(ServerConfig{UnixSocket: socket("done.sock")})
+
+	// 两次重新加载的后台协程同时等待，无论哪个先执行，最终生效的都是后一次的配置
+	for range 20 {
+		ws.mutex.Lock()
+		ws.applyAsync(ServerConfig{UnixSocket: socket("b.sock")})
+		ws.applyAsync(ServerConfig{UnixSocket: socket("c.sock")})
+		ws.mutex.Unlock()
+
+		waitFor(t, "重新绑定到 c.sock", func() bool {
+			return slices.Equal(listening(ws), []string{socket("c.sock")})
+		})
+		time.Sleep(10 * time.Millisecond)
+		if got := listening(ws); !slices.Equal(got, []string{socket("c.sock")}) {
+			t.Fatalf("监听地址为 %q，期望只有 c.sock", got)
+		}
+		ws.apply(ServerConfig{UnixSocket: socket("a.sock")})
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	stdinWriteTimeout = 5 * time.Second // 通过 API 写入标准输入的超时时间，进程不读取时避免请求一直阻塞
+	maxStdinBodyBytes = 1 << 20         // 通过 API 写入标准输入的最大请求体
+	stdinChunkSize    = 64 * 1024       // 写入初始内容时每次写入的字节数，每次写入单独计算超时
+)
+
+// processStdin 子进程标准输入管道的写端，进程运行期间由 keeper 持有
+type processStdin struct {
+	mutex  sync.Mutex
+	reader *os.File // 子进程持有的读端，启动后在 keeper 中关闭
+	writer *os.File
+	closed bool
+	count  *atomic.Int64 // 累计写入的字节数
+}
+
+// openStdin 按 stdin_data / stdin_file 为进程准备标准输入，返回要写入的初始内容。
+// 未配置标准输入时返回 nil，子进程的标准输入为 /dev/null
+func (pm *ProcessManager) openStdin(config ProcessConfig, cmd *exec.Cmd, count *atomic.Int64) (*processStdin, []byte, error) {
+	if config.StdinData == "" && config.StdinFile == "" && !config.StdinOpen {
+		return nil, nil, nil
+	}
+
+	data := []byte(config.StdinData)
+	if config.StdinFile != "" {
+		path := config.StdinFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(configDir(pm.configPath), path)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("读取 stdin_file 失败: %v", err)
+		}
+		data = content
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建标准输入管道失败: %v", err)
+	}
+	cmd.Stdin = reader
+	return &processStdin{reader: reader, writer: writer, count: count}, data, nil
+}
+
+// started 子进程启动后关闭 keeper 中的读端，写入初始内容，未启用 stdin_open 时随后关闭管道
+func (s *processStdin) started(name string, data []byte, keepOpen bool) {
+	s.reader.Close()
+
+	// 进程可能不会立即读取，在协程中写入，避免阻塞启动流程
+	go func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		// 与 Write 一样设置写入超时，进程一直不读取时不会永久占用锁；
+		// 内容较多时进程可能读得较慢，分块写入，每块重新计时
+		for len(data) > 0 && !s.closed {
+			chunk := data[:min(len(data), stdinChunkSize)]
+			s.writer.SetWriteDeadline(time.Now().Add(stdinWriteTimeout))
+			n, err := s.writer.Write(chunk)
+			s.count.Add(int64(n))
+			data = data[n:]
+			if err != nil && (n == 0 || !errors.Is(err, os.ErrDeadlineExceeded)) {
+				logWarnf("写入进程 %s 的标准输入失败: %v", name, err)
+				break
+			}
+		}
+		if !keepOpen {
+			s.closeLocked()
+		}
+	}()
+}
+
+// Write 向进程标准输入写入数据，超过 stdinWriteTimeout 仍未写完时返回错误
+func (s *processStdin) Write(p []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return 0, io.ErrClosedPipe
+	}
+	s.writer.SetWriteDeadline(time.Now().Add(stdinWriteTimeout))
+	n, err := s.writer.Write(p)
+	s.count.Add(int64(n))
+	return n, err
+}
+
+// Close 关闭管道，进程退出或启动失败时调用
+func (s *processStdin) Close() {
+	// 关闭写端会让阻塞中的写入立即返回，因此不等待锁
+	s.writer.Close()
+	s.reader.Close()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closed = true
+}
+
+// closeLocked 关闭写端，子进程读到 EOF，调用方需持有 s.mutex
+func (s *processStdin) closeLocked() {
+	if !s.closed {
+		s.writer.Close()
+		s.closed = true
+	}
+}
+
+// WriteStdin 向运行中且启用了 stdin_open 的进程写入标准输入
+func (pm *ProcessManager) WriteStdin(name string, data []byte) error {
+	pm.mutex.RLock()
+	status, exists := pm.processes[name]
+	if !exists {
+		pm.mutex.RUnlock()
+		return newProcessError(ErrProcessNotFound, "进程 %s 不存在", name)
+	}
+	procInfo, running := pm.commands[name]
+	if !running || status.PID == 0 {
+		pm.mutex.RUnlock()
+		return newProcessError(ErrProcessNotRunning, "进程 %s 没有运行", name)
+	}
+	stdin := procInfo.Stdin
+	pm.mutex.RUnlock()
+
+	// 写入可能阻塞到超时，不持有锁
+	if stdin == nil {
+		return newProcessError(ErrStdinClosed, "进程 %s 的标准输入没有保持打开，请配置 stdin_open", name)
+	}
+	if _, err := stdin.Write(data); err != nil {
+		if err == io.ErrClosedPipe {
+			return newProcessError(ErrStdinClosed, "进程 %s 的标准输入已关闭", name)
+		}
+		return fmt.Errorf("写入进程 %s 的标准输入失败: %v", name, err)
+	}
+
+	pm.mutex.Lock()
+	pm.addLog(name, fmt.Sprintf("INFO: 已写入标准输入 %d 字节", len(data)))
+	pm.mutex.Unlock()
+	return nil
+}
+
+// 写入标准输入 API
+func (pm *ProcessManager) handleStdin(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	name := r.PathValue("name")
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxStdinBodyBytes))
+	if err != nil {
+		// 只有超过大小限制才返回 413，客户端断开、读取超时等返回 400
+		status, message := http.StatusBadRequest, fmt.Sprintf("读取请求内容失败: %v", err)
+		if errors.As(err, new(*http.MaxBytesError)) {
+			status, message = http.StatusRequestEntityTooLarge, fmt.Sprintf("请求内容过大，最多 %d 字节", maxStdinBodyBytes)
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   message,
+		})
+		return
+	}
+
+	if err := pm.WriteStdin(name, data); err != nil {
+		w.WriteHeader(httpStatusFromError(err))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("已向进程 %s 写入 %d 字节", name, len(data)),
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// newTestStdin 创建 processStdin，返回管道真正的读端。
+// started 会关闭 processStdin 中的读端（模拟交给子进程后关闭），因此给它另一个管道的读端
+func newTestStdin(t *testing.T) (*processStdin, *os.File) {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unused, spare, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		reader.Close()
+		spare.Close()
+	})
+	return &processStdin{reader: unused, writer: writer, count: &atomic.Int64{}}, reader
+}
+
+func TestInitialStdinWriteTimesOut(t *testing.T) {
+	t.Parallel()
+	stdin, _ := newTestStdin(t)
+
+	// 没有人读取管道，初始内容超过管道缓冲后写入会一直阻塞，直到超时
+	stdin.started("svc", make([]byte, 1<<20), false)
+
+	waitFor(t, "初始内容写入超时后关闭管道", func() bool {
+		stdin.mutex.Lock()
+		defer stdin.mutex.Unlock()
+		return stdin.closed
+	})
+	if n := stdin.count.Load(); n == 0 || n >= 1<<20 {
+		t.Fatalf("写入了 %d 字节", n)
+	}
+}
+
+func TestInitialStdinSlowReader(t *testing.T) {
+	t.Parallel()
+	stdin, reader := newTestStdin(t)
+
+	// 读得慢但一直有进展时不算超时，内容全部写入
+	const size = 1 << 20
+	stdin.started("svc", make([]byte, size), false)
+
+	total := 0
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := reader.Read(buf)
+		total += n
+		if err != nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if total != size {
+		t.Fatalf("读取到 %d 字节，期望 %d", total, size)
+	}
+}
+
+func TestHandleStdinReadErrors(t *testing.T) {
+	pm, _ := newTestManager(t, `
+processes:
+  - name: svc
+    command: fake-sleep
+`)
+	handler := pm.routes()
+
+	tests := []struct {
+		body io.Reader
+		code int
+	}{
+		{strings.NewReader(strings.Repeat("x", maxStdinBodyBytes+1)), http.StatusRequestEntityTooLarge},
+		// 客户端断开等读取失败不是请求过大
+		{iotest.ErrReader(io.ErrUnexpectedEOF), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/process/svc/stdin", tt.body))
+		if recorder.Code != tt.code {
+			t.Errorf("返回 %d，期望 %d: %s", recorder.Code, tt.code, recorder.Body.String())
+		}
+	}
+}
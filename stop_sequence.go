@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// StopStep stop_sequence 中的一步：发送信号后最多等待 wait 秒，进程仍未退出时执行下一步
+type StopStep struct {
+	Signal string `json:"signal" yaml:"signal"` // 信号名称（如 SIGTERM、INT）或编号
+	Wait   int    `json:"wait" yaml:"wait"`     // 发送信号后等待进程退出的秒数
+}
+
+// defaultStopSequence 未配置 stop_sequence 时的停止方式：发送 SIGTERM，5 秒后仍未退出则强制杀死
+var defaultStopSequence = []StopStep{{Signal: "SIGTERM", Wait: 5}}
+
+// stopStep 解析后的停止步骤
+type stopStep struct {
+	signal syscall.Signal
+	wait   time.Duration
+}
+
+// validateStopSequence 校验 stop_sequence 中的信号和等待时间
+func validateStopSequence(steps []StopStep) error {
+	for i, step := range steps {
+		if _, err := parseSignal(step.Signal); err != nil {
+			return fmt.Errorf("第 %d 步: %v", i+1, err)
+		}
+		if step.Wait < 0 {
+			return fmt.Errorf("第 %d 步: wait 不能为负数", i+1)
+		}
+	}
+	return nil
+}
+
+// stopSteps 返回进程的停止步骤，未配置 stop_sequence 时使用默认步骤（配置加载时已校验）
+func stopSteps(config ProcessConfig) []stopStep {
+	sequence := config.StopSequence
+	if len(sequence) == 0 {
+		sequence = defaultStopSequence
+	}
+
+	steps := make([]stopStep, 0, len(sequence))
+	for _, step := range sequence {
+		sig, err := parseSignal(step.Signal)
+		if err != nil {
+			continue
+		}
+		steps = append(steps, stopStep{signal: sig, wait: time.Duration(step.Wait) * time.Second})
+	}
+	if len(steps) == 0 {
+		steps = append(steps, stopStep{signal: syscall.SIGTERM, wait: 5 * time.Second})
+	}
+	return steps
+}
+
+// stopWaitTotal 返回按停止步骤全部执行完所需的等待时间
+func stopWaitTotal(steps []stopStep) time.Duration {
+	var total time.Duration
+	for _, step := range steps {
+		total += step.wait
+	}
+	return total
+}
+
+// stopSignal 返回停止进程时首先发送的信号
+func stopSignal(config ProcessConfig) syscall.Signal {
+	return stopSteps(config)[0].signal
+}
+
+// signalStop 向进程（或其进程组）以及 forking 类型的守护进程发送信号
+func (p *ProcessInfo) signalStop(sig syscall.Signal) {
+	if pid := p.pid(); pid > 0 {
+		signalProcess(pid, sig, p.OwnGroup)
+	}
+	if daemonPID := int(p.DaemonPID.Load()); daemonPID > 0 {
+		syscall.Kill(daemonPID, sig)
+	}
+}
+
+// awaitStop 在第一步的信号发出后按停止步骤等待进程退出，每一步超时后发送下一步的信号，
+// 全部步骤后仍未退出时发送 SIGKILL。返回是否被强制杀死以及之前等待的总秒数
+func (pm *ProcessManager) awaitStop(name string, procInfo *ProcessInfo, steps []stopStep) (bool, int) {
+	var waited time.Duration
+	for i, step := range steps {
+		if i > 0 {
+			pm.mutex.Lock()
+			pm.addLog(name, fmt.Sprintf("WARNING: 进程未在 %d 秒内退出，发送 %s", int(waited.Seconds()), signalName(step.signal)))
+			pm.mutex.Unlock()
+			procInfo.signalStop(step.signal)
+		}
+
+		select {
+		case <-procInfo.Done:
+			return false, int(waited.Seconds())
+		case <-time.After(step.wait):
+			waited += step.wait
+		}
+	}
+
+	procInfo.signalStop(syscall.SIGKILL)
+	<-procInfo.Done // 等待 Wait() 完成
+	return true, int(waited.Seconds())
+}
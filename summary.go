@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ProcessSummary 所有进程的汇总统计
+type ProcessSummary struct {
+	Total    int            `json:"total"`
+	Running  int            `json:"running"`
+	Stopped  int            `json:"stopped"`
+	Error    int            `json:"error"`
+	Disabled int            `json:"disabled"`
+	Restarts int            `json:"restarts"`  // 所有进程的重启次数之和
+	ByStatus map[string]int `json:"by_status"` // 按状态统计的进程数，包含 starting、paused 等其他状态
+}
+
+// summarizeProcesses 统计各状态的进程数和总重启次数
+func summarizeProcesses(processes map[string]*ProcessStatus) ProcessSummary {
+	summary := ProcessSummary{
+		Total:    len(processes),
+		ByStatus: make(map[string]int),
+	}
+	for _, status := range processes {
+		summary.ByStatus[status.Status]++
+		summary.Restarts += status.Restarts
+	}
+	summary.Running = summary.ByStatus["running"]
+	summary.Stopped = summary.ByStatus["stopped"]
+	summary.Error = summary.ByStatus["error"]
+	summary.Disabled = summary.ByStatus["disabled"]
+	return summary
+}
+
+// 汇总统计 API
+func (pm *ProcessManager) handleSummary(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summarizeProcesses(pm.GetProcesses()))
+}
@@ -1,3 +1,28 @@
 package main
 
-var Version = "dev"
+import "runtime"
+
+// 构建信息，发布时通过 -ldflags "-X main.Version=... -X main.Commit=... -X main.BuildDate=..." 注入
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// BuildInfo 版本和构建信息
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+// getBuildInfo 获取当前二进制的构建信息
+func getBuildInfo() BuildInfo {
+	return BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+}
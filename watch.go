@@ -0,0 +1,186 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// defaultWatchDebounce 默认的变化合并时间（秒）
+const defaultWatchDebounce = 1.0
+
+// processWatcher 监视进程配置的路径，文件变化后重启进程
+type processWatcher struct {
+	name     string
+	paths    []string
+	debounce time.Duration
+	notify   *fsnotify.Watcher
+	stop     chan struct{}
+}
+
+// watchDebounce 返回变化合并时间，最后一次变化后经过该时间没有新变化才重启
+func watchDebounce(config ProcessConfig) time.Duration {
+	seconds := config.WatchDebounce
+	if seconds <= 0 {
+		seconds = defaultWatchDebounce
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// watchPaths 返回监视路径的绝对路径，相对路径相对于配置文件所在目录
+func (pm *ProcessManager) watchPaths(config ProcessConfig) []string {
+	paths := make([]string, 0, len(config.Watch))
+	for _, path := range config.Watch {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(configDir(pm.configPath), path)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// syncWatchers 按当前配置启动、更新或停止各进程的文件监视，调用者需持有锁。
+// 未启用或已从配置中移除的进程停止监视
+func (pm *ProcessManager) syncWatchers() {
+	for name, watcher := range pm.watchers {
+		status, exists := pm.processes[name]
+		if exists && status.Config.Enabled && slices.Equal(watcher.paths, pm.watchPaths(status.Config)) && watcher.debounce == watchDebounce(status.Config) {
+			continue
+		}
+		watcher.Close()
+		delete(pm.watchers, name)
+		logInfof("进程 %s 已停止监视文件变化", name)
+	}
+
+	for name, status := range pm.processes {
+		if _, exists := pm.watchers[name]; exists || !status.Config.Enabled || len(status.Config.Watch) == 0 {
+			continue
+		}
+		watcher, err := newProcessWatcher(name, pm.watchPaths(status.Config), watchDebounce(status.Config))
+		if err != nil {
+			logErrorf("进程 %s 无法监视文件变化: %v", name, err)
+			continue
+		}
+		pm.watchers[name] = watcher
+		go watcher.run(func() { pm.restartOnChange(name) })
+		logInfof("进程 %s 开始监视文件变化: %s", name, strings.Join(watcher.paths, ", "))
+	}
+}
+
+// restartOnChange 监视的文件变化后重启进程，被禁用或手动停止的进程不重启
+func (pm *ProcessManager) restartOnChange(name string) {
+	pm.mutex.Lock()
+	status, exists := pm.processes[name]
+	if !exists || !status.Config.Enabled || status.Status == "disabled" || (status.Status == "stopped" && status.StopReason == StopReasonManual) {
+		pm.mutex.Unlock()
+		return
+	}
+	pm.addLog(name, "INFO: 监视的文件发生变化，重启进程")
+	pm.mutex.Unlock()
+
+	logInfof("进程 %s 监视的文件发生变化，重启进程", name)
+	if err := pm.RestartProcess(name); err != nil {
+		logErrorf("文件变化后重启进程 %s 失败: %v", name, err)
+	}
+}
+
+// newProcessWatcher 创建 fsnotify 监视并注册所有监视路径：目录递归注册（跳过以 . 开头的子目录，如 .git），
+// 文件注册其所在目录，以便编辑器先删除再重建文件时仍能收到事件
+func newProcessWatcher(name string, paths []string, debounce time.Duration) (*processWatcher, error) {
+	notify, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &processWatcher{
+		name:     name,
+		paths:    paths,
+		debounce: debounce,
+		notify:   notify,
+		stop:     make(chan struct{}),
+	}
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			w.addTree(path)
+			continue
+		}
+		if err := notify.Add(filepath.Dir(path)); err != nil {
+			logWarnf("进程 %s 无法监视 %s: %v", name, path, err)
+		}
+	}
+	return w, nil
+}
+
+// addTree 递归注册目录及其子目录，跳过以 . 开头的子目录
+func (w *processWatcher) addTree(root string) {
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if err := w.notify.Add(path); err != nil {
+			logWarnf("进程 %s 无法监视目录 %s: %v", w.name, path, err)
+		}
+		return nil
+	})
+}
+
+// watched 判断事件路径是否在监视路径之下，文件路径只接受该文件本身的事件
+func (w *processWatcher) watched(path string) bool {
+	for _, root := range w.paths {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// run 处理文件事件，最后一次变化后等待 debounce 时间内不再变化才调用 onChange。
+// 新建的子目录会被注册监视；只修改权限的事件被忽略
+func (w *processWatcher) run(onChange func()) {
+	defer w.notify.Close()
+
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	for {
+		select {
+		case <-w.stop:
+			timer.Stop()
+			return
+		case event, ok := <-w.notify.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod || !w.watched(event.Name) {
+				continue
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if strings.HasPrefix(filepath.Base(event.Name), ".") {
+						continue
+					}
+					w.addTree(event.Name)
+				}
+			}
+			timer.Reset(w.debounce)
+		case err, ok := <-w.notify.Errors:
+			if !ok {
+				return
+			}
+			logWarnf("进程 %s 监视文件变化出错: %v", w.name, err)
+		case <-timer.C:
+			onChange()
+		}
+	}
+}
+
+// Close 停止监视，调用者持有锁时监视协程可能正在等待锁重启进程，因此不等待其退出
+func (w *processWatcher) Close() {
+	close(w.stop)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWatchRestartsOnChangeInNewDirectories(t *testing.T) {
+	pm, runner := newTestManager(t, `
+processes:
+  - name: web
+    command: fake-sleep
+    enabled: true
+    watch: [src]
+    watch_debounce: 0.1
+    restart_delay: 1
+`)
+	t.Cleanup(func() {
+		pm.mutex.Lock()
+		for _, watcher := range pm.watchers {
+			watcher.Close()
+		}
+		pm.mutex.Unlock()
+	})
+	if err := pm.StartProcess("web"); err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+
+	// 监视路径启动时还不存在，创建后其中新建的子目录也会被监视
+	src := filepath.Join(configDir(pm.configPath), "src")
+	steps := []func() error{
+		func() error { return os.Mkdir(src, 0755) },
+		func() error { return os.Mkdir(filepath.Join(src, "pkg"), 0755) },
+		func() error { return os.WriteFile(filepath.Join(src, "pkg", "main.go"), []byte("package main"), 0644) },
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, "文件变化后重启进程", func() bool { return len(runner.Calls()) == i+2 })
+	}
+}